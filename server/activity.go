@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type WeeklyVelocity struct {
+	Week          string  `json:"week"`
+	WeekStart     string  `json:"weekStart"`
+	Commits       int     `json:"commits"`
+	MovingAverage float64 `json:"movingAverage"`
+}
+
+type VelocityResponse struct {
+	Branch       string           `json:"branch,omitempty"`
+	Authors      []string         `json:"authors,omitempty"`
+	Window       int              `json:"window"`
+	TotalCommits int              `json:"totalCommits"`
+	Weeks        []WeeklyVelocity `json:"weeks"`
+}
+
+// utcDay truncates t to the start of its day in UTC.
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func isoWeekStart(t time.Time) time.Time {
+	t = utcDay(t)
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+func isoWeekLabel(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+func matchesAuthor(author identity, filter string) bool {
+	return strings.EqualFold(author.Name, filter) || strings.EqualFold(author.Email, filter)
+}
+
+func VelocityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	window := 4
+	if value := query.Get("window"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "window must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		window = n
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	branch := query.Get("branch")
+
+	iter, err := commitLog(r.Context(), repo, branch, filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	counts := map[time.Time]int{}
+	resp := VelocityResponse{Branch: branch, Authors: filter.Authors, Window: window}
+	if parseAnonymize(r) {
+		// Echoing the author filter would name who the numbers are for.
+		resp.Authors = nil
+	}
+
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		counts[isoWeekStart(c.Author.When.UTC())]++
+		resp.TotalCommits++
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp.Weeks = []WeeklyVelocity{}
+	if len(counts) == 0 {
+		writeJSON(w, r, resp)
+		return
+	}
+
+	var first, last time.Time
+	for week := range counts {
+		if first.IsZero() || week.Before(first) {
+			first = week
+		}
+		if week.After(last) {
+			last = week
+		}
+	}
+
+	// Weeks without commits are filled with zeros so the series is continuous.
+	sum := 0
+	for week := first; !week.After(last); week = week.AddDate(0, 0, 7) {
+		resp.Weeks = append(resp.Weeks, WeeklyVelocity{
+			Week:      isoWeekLabel(week),
+			WeekStart: week.Format("2006-01-02"),
+			Commits:   counts[week],
+		})
+
+		i := len(resp.Weeks) - 1
+		sum += resp.Weeks[i].Commits
+		if i >= window {
+			sum -= resp.Weeks[i-window].Commits
+		}
+		resp.Weeks[i].MovingAverage = float64(sum) / float64(min(i+1, window))
+	}
+
+	writeJSON(w, r, resp)
+}
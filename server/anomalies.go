@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// A day is flagged when its commit count lies more than anomalySigma
+// standard deviations from the mean of the anomalyWindow days before it.
+var (
+	anomalySigma  = envFloat("ANOMALY_SIGMA", 2)
+	anomalyWindow = envInt("ANOMALY_WINDOW_DAYS", 28)
+)
+
+type ActivityAnomaly struct {
+	Date    string `json:"date"`
+	Commits int    `json:"commits"`
+	// Kind is "high" for a burst of commits and "low" for a lull.
+	Kind        string  `json:"kind"`
+	Mean        float64 `json:"mean"`
+	StdDev      float64 `json:"stdDev"`
+	ExpectedMin float64 `json:"expectedMin"`
+	ExpectedMax float64 `json:"expectedMax"`
+}
+
+type AnomaliesResponse struct {
+	Window    int               `json:"window"`
+	Sigma     float64           `json:"sigma"`
+	Days      int               `json:"days"`
+	Anomalies []ActivityAnomaly `json:"anomalies"`
+}
+
+// dailyCounts turns per-day commit counts into a series from the first day
+// to the last, with days without commits filled in as zeros, and returns it
+// with its first day.
+func dailyCounts(counts map[time.Time]int) ([]int, time.Time) {
+	var first, last time.Time
+	for day := range counts {
+		if first.IsZero() || day.Before(first) {
+			first = day
+		}
+		if day.After(last) {
+			last = day
+		}
+	}
+	if first.IsZero() {
+		return nil, first
+	}
+	var series []int
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		series = append(series, counts[day])
+	}
+	return series, first
+}
+
+// AnomaliesHandler flags days whose commit count is unusually high or low
+// against a rolling baseline of the preceding window days. Days earlier than
+// a full window into history have no baseline and are never flagged. When
+// the baseline is flat every day that differs from it is flagged, so a burst
+// after weeks of silence still shows up.
+func AnomaliesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	resp := AnomaliesResponse{Window: anomalyWindow, Sigma: anomalySigma}
+	if value := query.Get("window"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 1 {
+			http.Error(w, "window must be an integer greater than 1", http.StatusBadRequest)
+			return
+		}
+		resp.Window = n
+	}
+	if value := query.Get("sigma"); value != "" {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || !(f > 0) || math.IsInf(f, 0) {
+			http.Error(w, "sigma must be a positive number", http.StatusBadRequest)
+			return
+		}
+		resp.Sigma = f
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, query.Get("branch"), filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	counts := map[time.Time]int{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if filter.matches(c) {
+			counts[utcDay(c.Author.When)]++
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	series, first := dailyCounts(counts)
+	resp.Days = len(series)
+	resp.Anomalies = []ActivityAnomaly{}
+	if len(series) == 0 {
+		writeJSON(w, r, resp)
+		return
+	}
+
+	// Running sums over the window before each day give its mean and
+	// variance without rescanning the window.
+	sum, sumSquares := 0.0, 0.0
+	for i, count := range series {
+		if i >= resp.Window {
+			n := float64(resp.Window)
+			mean := sum / n
+			stdDev := math.Sqrt(math.Max(sumSquares/n-mean*mean, 0))
+			low, high := mean-resp.Sigma*stdDev, mean+resp.Sigma*stdDev
+			value := float64(count)
+			if value > high || value < low {
+				kind := "high"
+				if value < low {
+					kind = "low"
+				}
+				resp.Anomalies = append(resp.Anomalies, ActivityAnomaly{
+					Date:        first.AddDate(0, 0, i).Format(time.DateOnly),
+					Commits:     count,
+					Kind:        kind,
+					Mean:        mean,
+					StdDev:      stdDev,
+					ExpectedMin: math.Max(low, 0),
+					ExpectedMax: high,
+				})
+			}
+			old := float64(series[i-resp.Window])
+			sum -= old
+			sumSquares -= old * old
+		}
+		sum += float64(count)
+		sumSquares += float64(count) * float64(count)
+	}
+
+	writeJSON(w, r, resp)
+}
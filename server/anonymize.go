@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// anonymizeKey keys the pseudonyms handed out for anonymize=true. It is
+// random per process unless ANONYMIZE_KEY is set, so a pseudonym cannot be
+// reversed by hashing a list of known addresses.
+var anonymizeKey = loadAnonymizeKey()
+
+func loadAnonymizeKey() []byte {
+	if key := os.Getenv("ANONYMIZE_KEY"); key != "" {
+		return []byte(key)
+	}
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// parseAnonymize reads anonymize=true, which replaces every author identity
+// in the response with a pseudonym.
+func parseAnonymize(r *http.Request) bool {
+	return r.URL.Query().Get("anonymize") == "true"
+}
+
+// pseudonym derives a stable "Contributor XXXXXX" name and an address under
+// the reserved .invalid domain from a person's email, or their name when
+// there is no email, ignoring case. The same person gets the same pseudonym
+// everywhere in a response, and across responses until the key changes.
+func pseudonym(name, email string) (string, string) {
+	key := strings.ToLower(email)
+	if key == "" {
+		key = strings.ToLower(name)
+	}
+	mac := hmac.New(sha256.New, anonymizeKey)
+	mac.Write([]byte(key))
+	id := base32.StdEncoding.EncodeToString(mac.Sum(nil))[:6]
+	return "Contributor " + id, strings.ToLower(id) + "@anonymized.invalid"
+}
+
+func pseudonymName(name, email string) string {
+	name, _ = pseudonym(name, email)
+	return name
+}
+
+// authorName is the name to show for sig, or its pseudonym with anonymize.
+func authorName(sig object.Signature, anonymize bool) string {
+	if anonymize {
+		return pseudonymName(sig.Name, sig.Email)
+	}
+	return sig.Name
+}
+
+// anonymizeMessage replaces the identities c names in its own message: the
+// "Name <email>" values of its trailers, trailers naming someone without an
+// address, and the author's and committer's addresses wherever they appear.
+// Names elsewhere in free text are left alone, since replacing them could
+// garble unrelated words.
+func anonymizeMessage(message string, c *object.Commit) string {
+	trailers := parseTrailers(c.Message)
+	var people []Person
+	people = append(people, trailers.SignedOffBy...)
+	people = append(people, trailers.CoAuthoredBy...)
+	people = append(people, trailers.ReviewedBy...)
+	people = append(people,
+		Person{Name: c.Author.Name, Email: c.Author.Email},
+		Person{Name: c.Committer.Name, Email: c.Committer.Email})
+
+	for _, person := range people {
+		name, email := pseudonym(person.Name, person.Email)
+		switch {
+		case person.Email == "":
+			// A trailer naming someone without an address.
+			message = strings.ReplaceAll(message, ": "+person.Name, ": "+name)
+		case person.Name != "":
+			message = strings.ReplaceAll(message, person.Name+" <"+person.Email+">", name+" <"+email+">")
+			fallthrough
+		default:
+			message = strings.ReplaceAll(message, person.Email, email)
+		}
+	}
+	return message
+}
+
+func (t CommitTrailers) anonymized() CommitTrailers {
+	anonymize := func(people []Person) []Person {
+		for i := range people {
+			people[i].Name, people[i].Email = pseudonym(people[i].Name, people[i].Email)
+		}
+		return people
+	}
+	return CommitTrailers{
+		SignedOffBy:  anonymize(t.SignedOffBy),
+		CoAuthoredBy: anonymize(t.CoAuthoredBy),
+		ReviewedBy:   anonymize(t.ReviewedBy),
+	}
+}
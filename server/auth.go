@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var unauthenticatedPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+func apiKeysFromEnv() []string {
+	var keys []string
+	for _, key := range strings.Split(os.Getenv("API_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func requestAPIKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+func validAPIKey(keys []string, candidate string) bool {
+	if candidate == "" {
+		return false
+	}
+	valid := false
+	for _, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// withAPIKeyAuth rejects requests without a valid API key. With no keys
+// configured every request is allowed, which keeps local development open.
+func withAPIKeyAuth(keys []string, next http.Handler) http.Handler {
+	if len(keys) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !unauthenticatedPaths[r.URL.Path] && !validAPIKey(keys, requestAPIKey(r)) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
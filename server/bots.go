@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// defaultBotPatterns catch the common automation accounts. Patterns are
+// matched case-insensitively against the author name and email, with * as
+// the only wildcard.
+var defaultBotPatterns = []string{
+	"*[bot]*",
+	"dependabot*",
+	"renovate*",
+	"github-actions*",
+	"greenkeeper*",
+	"snyk-bot*",
+	"*-bot@*",
+}
+
+var botPatterns = compileBotPatterns(botPatternsFromEnv())
+
+func botPatternsFromEnv() []string {
+	value := os.Getenv("BOT_PATTERNS")
+	if value == "" {
+		return defaultBotPatterns
+	}
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+func compileBotPatterns(patterns []string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		parts := strings.Split(pattern, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		compiled = append(compiled, regexp.MustCompile("(?i)^"+strings.Join(parts, ".*")+"$"))
+	}
+	return compiled
+}
+
+func isBot(author object.Signature) bool {
+	return matchesBotPatterns(botPatterns, author)
+}
+
+func matchesBotPatterns(patterns []*regexp.Regexp, author object.Signature) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(author.Name) || pattern.MatchString(author.Email) {
+			return true
+		}
+	}
+	return false
+}
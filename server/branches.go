@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+type BranchHead struct {
+	Name   string `json:"name"`
+	Remote bool   `json:"remote"`
+	Hash   string `json:"hash"`
+	Author string `json:"author"`
+	Email  string `json:"email"`
+	// CanonicalID is the key the author is counted under elsewhere, after
+	// the mailmap and IDENTITY_RULES. It is omitted with anonymize.
+	CanonicalID string `json:"canonicalId,omitempty"`
+	Date        string `json:"date"`
+	Subject     string `json:"subject"`
+
+	date time.Time
+}
+
+// BranchHeadsHandler returns the tip commit of every local and remote
+// branch, most recently committed first.
+func BranchHeadsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	refs, err := repo.References()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read references: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	mm := loadMailmap(repo)
+	anonymize := parseAnonymize(r)
+	heads := []BranchHead{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		// Symbolic refs such as origin/HEAD only alias another branch.
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		name := ref.Name()
+		if !name.IsBranch() && !name.IsRemote() {
+			return nil
+		}
+
+		c, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return err
+		}
+		author := mm.resolve(c.Author.Name, c.Author.Email)
+		key := canonicalID(author)
+		if anonymize {
+			author.Name, author.Email = pseudonym(author.Name, author.Email)
+		} else {
+			author.Email = redactEmail(author.Email)
+		}
+		heads = append(heads, BranchHead{
+			Name:        name.Short(),
+			Remote:      name.IsRemote(),
+			Hash:        c.Hash.String(),
+			Author:      author.Name,
+			Email:       author.Email,
+			CanonicalID: listedCanonicalID(key, anonymize),
+			Date:        c.Committer.When.Format(time.RFC3339),
+			Subject:     redactText(commitSubject(c.Message)),
+			date:        c.Committer.When,
+		})
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read branch heads: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	sort.Slice(heads, func(i, j int) bool {
+		if !heads[i].date.Equal(heads[j].date) {
+			return heads[i].date.After(heads[j].date)
+		}
+		return heads[i].Name < heads[j].Name
+	})
+
+	writeJSON(w, r, heads)
+}
+
+// BranchesHandler lists branch names as getBranches finds them, optionally
+// narrowed by a glob pattern matched against the short name (e.g. release/*).
+func BranchesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pattern := r.URL.Query().Get("pattern")
+	if _, err := path.Match(pattern, ""); err != nil {
+		http.Error(w, "pattern is not a valid glob", http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	branches, err := getBranches(repo)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get branches: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	matched := []string{}
+	for _, branch := range branches {
+		if pattern == "" {
+			matched = append(matched, branch)
+		} else if ok, _ := path.Match(pattern, branch); ok {
+			matched = append(matched, branch)
+		}
+	}
+	sort.Strings(matched)
+
+	writeJSON(w, r, matched)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestRepoHandlerBranches(t *testing.T) {
+	f := newFixtureRepo(t, fixtureCommit{Message: "Initial commit\n", Files: map[string]string{"a.txt": "a\n"}})
+	f.branch("feature/login")
+	f.branch("release")
+
+	body := strings.NewReader(`{"repoUrl": "` + fixtureURL + `"}`)
+	w := serve(RepoHandler, http.MethodPost, "/repo", body)
+
+	var got []string
+	for _, event := range sseEvents(w.Body.String()) {
+		if event[0] != "branches" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(event[1]), &got); err != nil {
+			t.Fatalf("decode branches event: %v", err)
+		}
+	}
+	sort.Strings(got)
+	if want := []string{"feature/login", "master", "release"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("branches = %v, want %v:\n%s", got, want, w.Body)
+	}
+}
+
+func TestBranchesHandler(t *testing.T) {
+	f := newFixtureRepo(t, fixtureCommit{Message: "Initial commit\n", Files: map[string]string{"a.txt": "a\n"}})
+	f.branch("feature/login")
+	f.branch("feature/search")
+	f.branch("release")
+	f.remoteBranch("origin", "master")
+	f.remoteBranch("origin", "release/1.0")
+	f.remoteBranch("upstream", "main")
+	if err := f.Repo.Storer.SetReference(plumbing.NewSymbolicReference("refs/remotes/origin/HEAD", "refs/remotes/origin/master")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		pattern    string
+		wantStatus int
+		want       []string
+	}{
+		{name: "all branches sorted", wantStatus: http.StatusOK, want: []string{"feature/login", "feature/search", "master", "release", "release/1.0", "upstream/main"}},
+		{name: "glob", pattern: "feature/*", wantStatus: http.StatusOK, want: []string{"feature/login", "feature/search"}},
+		{name: "remote-tracking only", pattern: "release/*", wantStatus: http.StatusOK, want: []string{"release/1.0"}},
+		{name: "no match", pattern: "hotfix-*", wantStatus: http.StatusOK, want: []string{}},
+		{name: "invalid glob", pattern: "[", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"repoId": {f.ID}}
+			if tt.pattern != "" {
+				query.Set("pattern", tt.pattern)
+			}
+			w := serve(BranchesHandler, http.MethodGet, "/branches?"+query.Encode(), nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got []string
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("branches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Remote-tracking names are listed the way history endpoints accept them.
+	for _, branch := range []string{"release/1.0", "upstream/main"} {
+		query := url.Values{"repoId": {f.ID}, "branch": {branch}}
+		if w := serve(CommitsHandler, http.MethodGet, "/commits?"+query.Encode(), nil); w.Code != http.StatusOK {
+			t.Errorf("commits on %s: status = %d: %s", branch, w.Code, w.Body)
+		}
+	}
+}
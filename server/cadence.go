@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const defaultCadenceTop = 10
+
+type CadenceByAuthor struct {
+	Top int `json:"top"`
+	// Authors maps each listed author to commits per hour of day, 0-23, in
+	// the timezone recorded on each commit.
+	Authors map[string][24]int `json:"authors"`
+	// Order lists the authors by total commits, most active first.
+	Order []string `json:"order"`
+	// OmittedAuthors counts the authors beyond top.
+	OmittedAuthors int `json:"omittedAuthors"`
+}
+
+// CadenceByAuthorHandler returns an hour-of-day commit histogram for each of
+// the top contributors, so working patterns can be compared. Hours use the
+// author's own UTC offset from the commit, which is what reflects their
+// working day. Authors are mailmap-resolved and bots are always left out.
+func CadenceByAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	top := defaultCadenceTop
+	if value := r.URL.Query().Get("top"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "top must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.ExcludeBots = true
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, r.URL.Query().Get("branch"), filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	mm := loadMailmap(repo)
+	anonymize := parseAnonymize(r)
+	labels := authorLabels{}
+	hours := map[string]*[24]int{}
+	totals := map[string]int{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		author := labels.label(mm.resolve(c.Author.Name, c.Author.Email), anonymize)
+		if hours[author] == nil {
+			hours[author] = &[24]int{}
+		}
+		hours[author][c.Author.When.Hour()]++
+		totals[author]++
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	authors := make([]string, 0, len(totals))
+	for author := range totals {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if totals[authors[i]] != totals[authors[j]] {
+			return totals[authors[i]] > totals[authors[j]]
+		}
+		return authors[i] < authors[j]
+	})
+
+	resp := CadenceByAuthor{Top: top, Authors: map[string][24]int{}}
+	if len(authors) > top {
+		resp.OmittedAuthors = len(authors) - top
+		authors = authors[:top]
+	}
+	resp.Order = authors
+	for _, author := range authors {
+		resp.Authors[author] = *hours[author]
+	}
+
+	writeJSON(w, r, resp)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+var errCloneCancelled = errors.New("clone cancelled")
+
+// runningClone is one clone in progress that can be cancelled.
+type runningClone struct {
+	cancel context.CancelCauseFunc
+}
+
+// cloneRegistry tracks the clones in progress by repository id so they can
+// be cancelled from another request.
+type cloneRegistry struct {
+	mu      sync.Mutex
+	running map[string]*runningClone
+}
+
+var runningClones = &cloneRegistry{running: map[string]*runningClone{}}
+
+// start derives a cancellable context for a clone of repoID. The returned
+// func must be called once the clone has finished.
+func (c *cloneRegistry) start(ctx context.Context, repoID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	clone := &runningClone{cancel: cancel}
+
+	c.mu.Lock()
+	c.running[repoID] = clone
+	c.mu.Unlock()
+
+	return ctx, func() {
+		c.mu.Lock()
+		if c.running[repoID] == clone {
+			delete(c.running, repoID)
+		}
+		c.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+func (c *cloneRegistry) cancel(repoID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clone, ok := c.running[repoID]
+	if ok {
+		clone.cancel(errCloneCancelled)
+	}
+	return ok
+}
+
+// isRunning reports whether repoID is being cloned right now.
+func (c *cloneRegistry) isRunning(repoID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.running[repoID]
+	return ok
+}
+
+// CancelCloneHandler aborts the clone of repoId that another request is
+// running. The clone stops at its next context check, its partial directory
+// is removed, and the cloning request's stream ends with a "Clone cancelled"
+// error. A repository that is already on disk answers 409.
+func CancelCloneHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Only DELETE method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	repoID := r.URL.Query().Get("repoId")
+	if repoID == "" {
+		http.Error(w, "repoId is required", http.StatusBadRequest)
+		return
+	}
+	resolved, err := resolveRepoID(repoID)
+	if err != nil {
+		http.Error(w, repoErrorMessage(err), repoErrorStatus(err))
+		return
+	}
+
+	if !runningClones.cancel(resolved) {
+		http.Error(w, "Repository is not being cloned; the clone has already completed", http.StatusConflict)
+		return
+	}
+
+	loggerFrom(r.Context()).Info("Clone cancelled", "repoId", resolved)
+	writeJSON(w, r, map[string]string{"repoId": resolved, "status": "cancelled"})
+}
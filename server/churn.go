@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type LanguageChurn struct {
+	Language  string `json:"language"`
+	Files     int    `json:"files"`
+	Commits   int    `json:"commits"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Churn     int    `json:"churn"`
+}
+
+// churnLanguage groups files the language breakdown does not recognise by
+// their extension, or as "Other" for dotfiles and extensionless names, so
+// their activity is still visible.
+func churnLanguage(file string) string {
+	if lang := languageForPath(file); lang != "" {
+		return lang
+	}
+	base := path.Base(file)
+	if ext := path.Ext(base); ext != "" && ext != base {
+		return strings.ToLower(ext)
+	}
+	return "Other"
+}
+
+// ChurnByLanguageHandler aggregates additions and deletions across history
+// per language, skipping the same vendored and build paths as the language
+// breakdown.
+func ChurnByLanguageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, "", filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	files := churnCounter{}
+	languageCommits := map[string]int{}
+	err = forEachCommitStats(repoID, iter, filter.matches, func(c *object.Commit, modifications []FileModification) error {
+		touched := map[string]bool{}
+		for _, mod := range modifications {
+			if isIgnoredPath(mod.File) || !filter.includesFile(mod.File) {
+				continue
+			}
+			files.add(mod, c.Author.When)
+			touched[churnLanguage(mod.File)] = true
+		}
+		for lang := range touched {
+			languageCommits[lang]++
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	byLanguage := map[string]*LanguageChurn{}
+	for _, file := range files {
+		lang := churnLanguage(file.File)
+		stat := byLanguage[lang]
+		if stat == nil {
+			stat = &LanguageChurn{Language: lang, Commits: languageCommits[lang]}
+			byLanguage[lang] = stat
+		}
+		stat.Files++
+		stat.Additions += file.Additions
+		stat.Deletions += file.Deletions
+		stat.Churn += file.Churn
+	}
+
+	churn := make([]LanguageChurn, 0, len(byLanguage))
+	for _, stat := range byLanguage {
+		churn = append(churn, *stat)
+	}
+	sort.Slice(churn, func(i, j int) bool {
+		if churn[i].Churn != churn[j].Churn {
+			return churn[i].Churn > churn[j].Churn
+		}
+		return churn[i].Language < churn[j].Language
+	})
+
+	writeJSON(w, r, churn)
+}
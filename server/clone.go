@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/sync/semaphore"
+)
+
+var errSSHKeyForNonSSHURL = errors.New("an SSH private key was provided for a non-SSH repository URL")
+
+var errOriginMismatch = errors.New("the existing repository does not match the requested URL")
+
+// gcAfterClone enables repackRepository after every fresh clone. It is off by
+// default because repacking rewrites the whole object store.
+var gcAfterClone = os.Getenv("GC_AFTER_CLONE") == "true"
+
+var (
+	// cloneSlots caps how many clones run at once across every entry point;
+	// it is nil when MAX_CONCURRENT_CLONES is 0.
+	cloneSlots       = newCloneSlots(maxConcurrentClones)
+	cloneSlotTimeout = time.Duration(envInt("CLONE_SLOT_TIMEOUT_SECONDS", 30)) * time.Second
+)
+
+func newCloneSlots(n int64) *semaphore.Weighted {
+	if n <= 0 {
+		return nil
+	}
+	return semaphore.NewWeighted(n)
+}
+
+// acquireCloneSlot waits up to CLONE_SLOT_TIMEOUT_SECONDS for a clone slot.
+// On success release must be called once the clone has finished.
+func acquireCloneSlot(ctx context.Context) (release func(), err error) {
+	if cloneSlots == nil {
+		return func() {}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, cloneSlotTimeout)
+	defer cancel()
+	if err := cloneSlots.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	return func() { cloneSlots.Release(1) }, nil
+}
+
+// cloneAuth builds the transport auth for a clone request. Host keys are
+// checked against SSH_KNOWN_HOSTS (or ~/.ssh/known_hosts) unless
+// SSH_INSECURE_SKIP_HOST_KEY_CHECK=true is set on the server.
+func cloneAuth(req CloneRequest) (transport.AuthMethod, error) {
+	if req.SSHPrivateKey == "" {
+		return nil, nil
+	}
+
+	endpoint, err := transport.NewEndpoint(req.RepoURL)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Protocol != "ssh" {
+		return nil, errSSHKeyForNonSSHURL
+	}
+
+	user := endpoint.User
+	if user == "" {
+		user = "git"
+	}
+
+	auth, err := gitssh.NewPublicKeys(user, []byte(req.SSHPrivateKey), req.SSHPassphrase)
+	if err != nil {
+		// The underlying error is not returned so nothing about the key can
+		// leak into responses or logs.
+		return nil, errors.New("invalid SSH private key or passphrase")
+	}
+
+	if os.Getenv("SSH_INSECURE_SKIP_HOST_KEY_CHECK") == "true" {
+		auth.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+	} else {
+		callback, err := gitssh.NewKnownHostsCallback()
+		if err != nil {
+			return nil, errors.New("failed to load SSH known hosts: " + err.Error())
+		}
+		auth.HostKeyCallback = callback
+	}
+
+	return auth, nil
+}
+
+type cloneOptions struct {
+	Auth     transport.AuthMethod
+	Bare     bool
+	Progress io.Writer
+	// BeforeClone, when set, is called once it is clear that a clone rather
+	// than an open of an existing repository is about to start.
+	BeforeClone func()
+	// Refs limits the clone to these refspecs instead of every branch and
+	// tag. It has no effect when the repository is already on disk.
+	Refs []config.RefSpec
+	// LocalPath is the checkout a local registration links to. Without a
+	// URL to compare origins with, the link is checked against it instead.
+	LocalPath string
+}
+
+// cloneOrOpen is the single entry point for getting a repository onto disk.
+// It opens the repository for repoID if it already exists, after checking
+// that it is a clone of repoURL (or links to opts.LocalPath), and clones
+// repoURL otherwise. Calls for the same repoID take turns, so a request that
+// arrives during a clone waits for it and then opens the result. A failed
+// clone removes the directory it created so a retry starts clean. The
+// returned bool reports whether a clone was attempted. A clone can be
+// aborted through runningClones, which makes it fail with errCloneCancelled.
+func cloneOrOpen(ctx context.Context, repoURL, repoID string, opts cloneOptions) (*git.Repository, bool, error) {
+	repoPath := filepath.Join(reposDir, repoID)
+
+	unlock, err := cloneLocks.lock(ctx, repoID)
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(repoPath); err == nil {
+		repo, err := openRepository(repoPath)
+		if err == nil && repoURL != "" {
+			err = checkOrigin(repo, repoURL)
+		} else if err == nil && opts.LocalPath != "" {
+			err = checkLocalLink(repoPath, opts.LocalPath)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return repo, false, nil
+	} else if !os.IsNotExist(err) {
+		return nil, false, err
+	}
+
+	if opts.BeforeClone != nil {
+		opts.BeforeClone()
+	}
+
+	activeClones.Add(1)
+	defer activeClones.Add(-1)
+
+	ctx, done := runningClones.start(ctx, repoID)
+	defer done()
+
+	// Creating the directory up front makes it ours: a failed clone only
+	// ever removes what this call created.
+	if err := os.Mkdir(repoPath, os.ModePerm); err != nil {
+		return nil, true, err
+	}
+
+	var repo *git.Repository
+	if len(opts.Refs) > 0 {
+		repo, err = cloneRefs(ctx, repoPath, repoURL, opts)
+	} else {
+		repo, err = git.PlainCloneContext(ctx, repoPath, opts.Bare, &git.CloneOptions{
+			URL:      repoURL,
+			Auth:     opts.Auth,
+			Progress: opts.Progress,
+		})
+	}
+	if err != nil {
+		if !errors.Is(err, git.ErrRepositoryAlreadyExists) {
+			os.RemoveAll(repoPath)
+		}
+		if errors.Is(context.Cause(ctx), errCloneCancelled) {
+			err = errCloneCancelled
+		}
+		return nil, true, err
+	}
+	return repo, true, nil
+}
+
+// cloneLocks makes cloneOrOpen take turns per repository id.
+var cloneLocks = &repoLocks{locks: map[string]*repoLock{}}
+
+// repoLocks is a set of mutexes keyed by repository id. An entry lives only
+// while some call holds or waits for it.
+type repoLocks struct {
+	mu    sync.Mutex
+	locks map[string]*repoLock
+}
+
+type repoLock struct {
+	held  chan struct{}
+	users int
+}
+
+// lock waits until repoID is free or ctx is done. On success unlock must be
+// called once.
+func (l *repoLocks) lock(ctx context.Context, repoID string) (unlock func(), err error) {
+	l.mu.Lock()
+	lock, ok := l.locks[repoID]
+	if !ok {
+		lock = &repoLock{held: make(chan struct{}, 1)}
+		l.locks[repoID] = lock
+	}
+	lock.users++
+	l.mu.Unlock()
+
+	leave := func() {
+		l.mu.Lock()
+		if lock.users--; lock.users == 0 {
+			delete(l.locks, repoID)
+		}
+		l.mu.Unlock()
+	}
+	select {
+	case lock.held <- struct{}{}:
+		return func() {
+			<-lock.held
+			leave()
+		}, nil
+	case <-ctx.Done():
+		leave()
+		return nil, ctx.Err()
+	}
+}
+
+// checkOrigin makes sure an existing directory is reused only for the
+// repository it was cloned from. Ids keep just a short hash of the URL, and a
+// directory left by an older layout or copied in by hand can sit under an id
+// it does not belong to; serving it would answer with another repository's
+// data. Like ids, the comparison ignores only the case of the scheme and
+// host, and a trailing "/" or ".git".
+func checkOrigin(repo *git.Repository, repoURL string) error {
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if errors.Is(err, git.ErrRemoteNotFound) || err == nil && len(remote.Config().URLs) == 0 {
+		return fmt.Errorf("%w: it has no origin remote", errOriginMismatch)
+	}
+	if err != nil {
+		return err
+	}
+	if origin := remote.Config().URLs[0]; normalizeRepoURL(origin) != normalizeRepoURL(repoURL) {
+		return fmt.Errorf("%w: it is a clone of %s", errOriginMismatch, maskRemoteURL(origin))
+	}
+	return nil
+}
+
+// repackRepository is the closest go-git gets to `git gc`: it writes every
+// reachable object into a single new pack and removes the packs that existed
+// before. There is no equivalent of git's reflog expiry, loose object pruning
+// or commit-graph writing; a fresh clone has no loose objects anyway. The
+// repository is reopened afterwards because the old handle still points at
+// the deleted packs, and the reopened one is returned even if repacking
+// failed part way.
+func repackRepository(repo *git.Repository, repoID string) (*git.Repository, error) {
+	err := repo.RepackObjects(&git.RepackConfig{OnlyDeletePacksOlderThan: time.Now()})
+	reopened, openErr := openRepository(filepath.Join(reposDir, repoID))
+	if openErr != nil {
+		return nil, openErr
+	}
+	return reopened, err
+}
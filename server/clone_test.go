@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCloneOrOpenWaitsForRunningClone(t *testing.T) {
+	f := newFixtureRepo(t, fixtureCommit{Message: "Initial commit\n", Files: map[string]string{"a.txt": "a\n"}})
+	const repoID = "copy-00000000"
+
+	type result struct {
+		cloned bool
+		err    error
+	}
+	started, proceed := make(chan struct{}), make(chan struct{})
+	first := make(chan result, 1)
+	go func() {
+		_, cloned, err := cloneOrOpen(context.Background(), f.Path, repoID, cloneOptions{
+			BeforeClone: func() {
+				close(started)
+				<-proceed
+			},
+		})
+		first <- result{cloned, err}
+	}()
+	<-started
+
+	second := make(chan result, 1)
+	go func() {
+		_, cloned, err := cloneOrOpen(context.Background(), f.Path, repoID, cloneOptions{
+			BeforeClone: func() { t.Error("second call started a clone of its own") },
+		})
+		second <- result{cloned, err}
+	}()
+	select {
+	case got := <-second:
+		t.Fatalf("second call returned during the clone: %+v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(proceed)
+
+	if got := <-first; !got.cloned || got.err != nil {
+		t.Fatalf("first call = %+v, want a successful clone", got)
+	}
+	if got := <-second; got.cloned || got.err != nil {
+		t.Fatalf("second call = %+v, want the clone opened", got)
+	}
+	if _, err := openRepository(filepath.Join(reposDir, repoID)); err != nil {
+		t.Errorf("clone is not a usable repository: %v", err)
+	}
+}
+
+func TestCloneOrOpenRemovesFailedClone(t *testing.T) {
+	newFixtureRepo(t)
+	const repoID = "missing-00000000"
+
+	_, cloned, err := cloneOrOpen(context.Background(), filepath.Join(t.TempDir(), "missing"), repoID, cloneOptions{})
+	if err == nil || !cloned {
+		t.Fatalf("cloneOrOpen = %t, %v; want a failed clone", cloned, err)
+	}
+	if _, err := os.Stat(filepath.Join(reposDir, repoID)); !os.IsNotExist(err) {
+		t.Errorf("partial clone left behind: %v", err)
+	}
+	if n := len(cloneLocks.locks); n != 0 {
+		t.Errorf("%d repository locks left behind", n)
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	f := newFixtureRepo(t)
+
+	tests := []struct {
+		name    string
+		repoURL string
+		wantErr bool
+	}{
+		{name: "same URL", repoURL: fixtureURL},
+		{name: "host case and .git", repoURL: "https://EXAMPLE.com/acme/fixture/"},
+		{name: "path case", repoURL: "https://example.com/acme/Fixture.git", wantErr: true},
+		{name: "other repository", repoURL: "https://example.com/acme/other.git", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOrigin(f.Repo, tt.repoURL)
+			if tt.wantErr != errors.Is(err, errOriginMismatch) || !tt.wantErr && err != nil {
+				t.Errorf("checkOrigin = %v, want mismatch = %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCloneOrOpenChecksLocalLink(t *testing.T) {
+	newFixtureRepo(t)
+	paths := localCheckouts(t, "app", "other")
+	repoID, path, err := registerLocalRepo(paths["app"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, cloned, err := cloneOrOpen(context.Background(), "", repoID, cloneOptions{LocalPath: path}); err != nil || cloned {
+		t.Fatalf("cloneOrOpen = %t, %v; want the registration opened", cloned, err)
+	}
+
+	// The link was repointed after registering, e.g. by hand.
+	link := filepath.Join(reposDir, repoID)
+	if err := os.Remove(link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(paths["other"], link); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cloneOrOpen(context.Background(), "", repoID, cloneOptions{LocalPath: path}); !errors.Is(err, errLocalRepoConflict) {
+		t.Errorf("cloneOrOpen = %v, want a conflict", err)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// maxCloneRefs bounds the refs a single clone request may list.
+const maxCloneRefs = 100
+
+var errNoRefsFetched = errors.New("none of the requested refs exist on the remote")
+
+// parseRefSpecs turns the refs of a clone request into fetch refspecs. A
+// branch name or a full ref name fetches that ref to where a normal clone
+// would put it: refs/remotes/origin/ for branches, the same name for tags
+// and other refs. An entry with a colon is used as a refspec as written.
+func parseRefSpecs(refs []string) ([]config.RefSpec, error) {
+	if len(refs) > maxCloneRefs {
+		return nil, fmt.Errorf("refs must list at most %d entries", maxCloneRefs)
+	}
+	specs := make([]config.RefSpec, 0, len(refs))
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		spec := config.RefSpec(ref)
+		if !strings.Contains(ref, ":") {
+			spec = defaultRefSpec(ref)
+		}
+		if ref == "" || spec.Validate() != nil || spec.IsDelete() {
+			return nil, fmt.Errorf("invalid ref %q", ref)
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func defaultRefSpec(ref string) config.RefSpec {
+	name := plumbing.ReferenceName(ref)
+	switch {
+	case name.IsBranch():
+		return config.RefSpec(fmt.Sprintf("+%s:refs/remotes/origin/%s", name, name.Short()))
+	case strings.HasPrefix(ref, "refs/"):
+		return config.RefSpec(fmt.Sprintf("+%s:%s", name, name))
+	default:
+		return config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", ref, ref))
+	}
+}
+
+// cloneRefs clones only the given refspecs. go-git's clone fetches every
+// branch, so this initialises the repository, adds origin with the refspecs
+// as its fetch config and fetches them without following tags. HEAD is set
+// to a local branch for the first fetched remote branch, or detached at the
+// first other fetched ref, and the worktree checked out from it.
+func cloneRefs(ctx context.Context, repoPath, repoURL string, opts cloneOptions) (*git.Repository, error) {
+	repo, err := git.PlainInit(repoPath, opts.Bare)
+	if err != nil {
+		return nil, err
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name:  git.DefaultRemoteName,
+		URLs:  []string{repoURL},
+		Fetch: opts.Refs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   opts.Refs,
+		Auth:       opts.Auth,
+		Progress:   opts.Progress,
+		Tags:       git.NoTags,
+	})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, errNoRefsFetched
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fetched, err := fetchedRefs(repo, opts.Refs)
+	if err != nil {
+		return nil, err
+	}
+	if len(fetched) == 0 {
+		return nil, errNoRefsFetched
+	}
+	return repo, checkoutFetched(repo, fetched, opts.Bare)
+}
+
+// fetchedRefs lists the references the refspecs wrote, in refspec order.
+func fetchedRefs(repo *git.Repository, specs []config.RefSpec) ([]*plumbing.Reference, error) {
+	refs, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+	var all []*plumbing.Reference
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			all = append(all, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var fetched []*plumbing.Reference
+	seen := map[plumbing.ReferenceName]bool{}
+	for _, spec := range specs {
+		local := spec.Reverse()
+		for _, ref := range all {
+			if !seen[ref.Name()] && local.Match(ref.Name()) {
+				seen[ref.Name()] = true
+				fetched = append(fetched, ref)
+			}
+		}
+	}
+	return fetched, nil
+}
+
+func checkoutFetched(repo *git.Repository, fetched []*plumbing.Reference, bare bool) error {
+	head := fetched[0]
+	for _, ref := range fetched {
+		if ref.Name().IsRemote() {
+			head = ref
+			break
+		}
+	}
+
+	var checkout git.CheckoutOptions
+	if head.Name().IsRemote() {
+		_, short, _ := strings.Cut(head.Name().Short(), "/")
+		branch := plumbing.NewBranchReferenceName(short)
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, head.Hash())); err != nil {
+			return err
+		}
+		if bare {
+			return repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch))
+		}
+		checkout.Branch = branch
+	} else {
+		hash := head.Hash()
+		if tag, err := repo.TagObject(hash); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				return err
+			}
+			hash = commit.Hash
+		}
+		if bare {
+			return repo.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, hash))
+		}
+		checkout.Hash = hash
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return wt.Checkout(&checkout)
+}
+
+// refNames returns the full names of refs for reporting.
+func refNames(refs []*plumbing.Reference) []string {
+	names := make([]string, len(refs))
+	for i, ref := range refs {
+		names[i] = ref.Name().String()
+	}
+	return names
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// maxCodeAgeFiles caps how many files a directory view blames, since blame
+// walks the history of every file it is asked about.
+var maxCodeAgeFiles = envInt("CODE_AGE_MAX_FILES", 50)
+
+var codeAgeBuckets = []struct {
+	label  string
+	before time.Duration
+}{
+	{"<1mo", 30 * 24 * time.Hour},
+	{"1-6mo", 182 * 24 * time.Hour},
+	{"6-12mo", 365 * 24 * time.Hour},
+	{">1yr", 0},
+}
+
+type CodeAgeBucket struct {
+	Label      string  `json:"label"`
+	Lines      int     `json:"lines"`
+	Percentage float64 `json:"percentage"`
+}
+
+type CodeAgeFile struct {
+	Path    string          `json:"path"`
+	Lines   int             `json:"lines"`
+	Buckets []CodeAgeBucket `json:"buckets"`
+}
+
+type CodeAgeResponse struct {
+	Commit     string          `json:"commit"`
+	Path       string          `json:"path"`
+	TotalLines int             `json:"totalLines"`
+	Buckets    []CodeAgeBucket `json:"buckets"`
+	Files      []CodeAgeFile   `json:"files"`
+	// Truncated is set when the path holds more files than
+	// CODE_AGE_MAX_FILES and only the first ones were blamed.
+	Truncated bool `json:"truncated"`
+}
+
+func newCodeAgeBuckets() []CodeAgeBucket {
+	buckets := make([]CodeAgeBucket, len(codeAgeBuckets))
+	for i, bucket := range codeAgeBuckets {
+		buckets[i].Label = bucket.label
+	}
+	return buckets
+}
+
+func codeAgeBucket(age time.Duration) int {
+	for i, bucket := range codeAgeBuckets {
+		if bucket.before == 0 || age < bucket.before {
+			return i
+		}
+	}
+	return len(codeAgeBuckets) - 1
+}
+
+func setCodeAgePercentages(buckets []CodeAgeBucket, total int) {
+	if total == 0 {
+		return
+	}
+	for i := range buckets {
+		buckets[i].Percentage = float64(buckets[i].Lines) / float64(total) * 100
+	}
+}
+
+// codeAgeFiles lists the text files at or under dir in the commit's tree,
+// in path order. Vendored, generated, binary and LFS files are skipped.
+func codeAgeFiles(commit *object.Commit, dir string) ([]string, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if dir != "" && f.Name != dir && !strings.HasPrefix(f.Name, dir+"/") {
+			return nil
+		}
+		if isIgnoredPath(f.Name) {
+			return nil
+		}
+		if binary, err := f.IsBinary(); err != nil || binary {
+			return nil
+		}
+		if _, lfs := lfsPointerSize(f); lfs {
+			return nil
+		}
+		files = append(files, f.Name)
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// CodeAgeHandler blames a file, or up to CODE_AGE_MAX_FILES files under a
+// directory, and buckets the current lines by the author date of the commit
+// that last changed them.
+func CodeAgeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	dir := strings.Trim(query.Get("path"), "/")
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	// Blame needs every commit that touched a line, so it cannot run on
+	// a shallow clone at all.
+	if len(shallowBoundary(repo)) > 0 {
+		http.Error(w, "Repository is a shallow clone; code age needs its full history", http.StatusConflict)
+		return
+	}
+
+	commit, err := tipCommit(r.Context(), repo, query.Get("branch"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	files, err := codeAgeFiles(commit, dir)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read tree: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if len(files) == 0 {
+		http.Error(w, "No text files found at path", http.StatusNotFound)
+		return
+	}
+
+	resp := CodeAgeResponse{
+		Commit:  commit.Hash.String(),
+		Path:    dir,
+		Buckets: newCodeAgeBuckets(),
+		Files:   []CodeAgeFile{},
+	}
+	if len(files) > maxCodeAgeFiles {
+		files = files[:maxCodeAgeFiles]
+		resp.Truncated = true
+	}
+
+	now := time.Now()
+	ctx := r.Context()
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return
+		}
+		blame, err := git.Blame(commit, file)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to blame %s: %v", file, err), http.StatusInternalServerError)
+			return
+		}
+
+		entry := CodeAgeFile{Path: file, Lines: len(blame.Lines), Buckets: newCodeAgeBuckets()}
+		for _, line := range blame.Lines {
+			i := codeAgeBucket(now.Sub(line.Date))
+			entry.Buckets[i].Lines++
+			resp.Buckets[i].Lines++
+		}
+		setCodeAgePercentages(entry.Buckets, entry.Lines)
+		resp.TotalLines += entry.Lines
+		resp.Files = append(resp.Files, entry)
+	}
+	setCodeAgePercentages(resp.Buckets, resp.TotalLines)
+
+	writeJSON(w, r, resp)
+}
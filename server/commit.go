@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// mainlineChildren caches, per repository and branch tip, the first-parent
+// child of every commit on the tip's first-parent history. Git only stores
+// parent pointers, so finding a child means walking down from the tip; a
+// tip's history never changes, so the walk is done once per tip.
+var mainlineChildren = newLRUCache[string, map[plumbing.Hash]plumbing.Hash](16)
+
+type CommitDetail struct {
+	Commit
+	// FirstParent and FirstChild are the previous and next commit on the
+	// branch's first-parent mainline, set with navigation=true.
+	// FirstChild is omitted at the branch tip and for commits that are not
+	// on the mainline, which OnMainline tells apart.
+	FirstParent string `json:"firstParent,omitempty"`
+	FirstChild  string `json:"firstChild,omitempty"`
+	OnMainline  *bool  `json:"onMainline,omitempty"`
+}
+
+// mainlineChildMap maps each commit on tip's first-parent history to its
+// child on that history. The walk stops at a shallow clone's boundary.
+func mainlineChildMap(repo *git.Repository, repoID string, tip *object.Commit) (map[plumbing.Hash]plumbing.Hash, error) {
+	key := repoID + "\x00" + tip.Hash.String()
+	if children, ok := mainlineChildren.get(key); ok {
+		return children, nil
+	}
+
+	children := map[plumbing.Hash]plumbing.Hash{tip.Hash: plumbing.ZeroHash}
+	for c := tip; c.NumParents() > 0; {
+		parent, err := repo.CommitObject(c.ParentHashes[0])
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		children[parent.Hash] = c.Hash
+		c = parent
+	}
+	mainlineChildren.put(key, children)
+	return children, nil
+}
+
+// CommitHandler returns a single commit with its per-file stats. With
+// navigation=true it adds the previous and next commit along the first-parent
+// mainline of branch (HEAD by default) so a UI can step through history.
+func CommitHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	rev := query.Get("hash")
+	if rev == "" {
+		http.Error(w, "hash is required", http.StatusBadRequest)
+		return
+	}
+	commitOpts, err := parseCommitOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	c, err := resolveCommit(repo, rev)
+	if err != nil {
+		http.Error(w, revisionErrorMessage(rev, err), logErrorStatus(err))
+		return
+	}
+
+	detail := CommitDetail{Commit: newCommit(c, commitOpts)}
+	modifications, err := commitModifications(c)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+	detail.setModifications(modifications)
+
+	if query.Get("navigation") == "true" {
+		tip, err := tipCommit(r.Context(), repo, query.Get("branch"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+			return
+		}
+		children, err := mainlineChildMap(repo, repoID, tip)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to walk mainline: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if c.NumParents() > 0 {
+			detail.FirstParent = c.ParentHashes[0].String()
+		}
+		child, onMainline := children[c.Hash]
+		if !child.IsZero() {
+			detail.FirstChild = child.String()
+		}
+		detail.OnMainline = &onMainline
+	}
+
+	writeJSON(w, r, detail)
+}
@@ -0,0 +1,525 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+type Commit struct {
+	Hash             string             `json:"hash"`
+	TreeHash         string             `json:"treeHash"`
+	Author           string             `json:"author"`
+	Email            string             `json:"email"`
+	Message          string             `json:"message"`
+	MessageTruncated bool               `json:"messageTruncated"`
+	Date             string             `json:"date"`
+	HumanDate        string             `json:"humanDate"`
+	Bot              bool               `json:"bot"`
+	Empty            bool               `json:"empty"`
+	Modifications    []FileModification `json:"modifications,omitempty"`
+	// ChurnRatio is deletions over additions across Modifications. It is
+	// omitted when the commit has no stats or adds no lines.
+	ChurnRatio     *float64 `json:"churnRatio,omitempty"`
+	LikelyRefactor bool     `json:"likelyRefactor,omitempty"`
+	CommitTrailers
+}
+
+const defaultMaxBodyLength = 500
+
+// A commit is flagged as a likely refactor (mass move, rename or cleanup)
+// when it touches at least refactorMinFiles files and deletes at least
+// refactorMinChurnRatio lines per line added, or adds none at all.
+var (
+	refactorMinFiles      = envInt("REFACTOR_MIN_FILES", 10)
+	refactorMinChurnRatio = envFloat("REFACTOR_MIN_CHURN_RATIO", 0.8)
+)
+
+// commitOptions controls how commits are rendered in responses and is shared
+// by every endpoint that returns Commit values.
+type commitOptions struct {
+	FullMessage   bool
+	MaxBodyLength int
+	// ExactHumanDate selects the two-unit form of humanDate.
+	ExactHumanDate bool
+	// Anonymize replaces the author and the people in trailers and the
+	// message with pseudonyms.
+	Anonymize bool
+}
+
+func parseCommitOptions(r *http.Request) (commitOptions, error) {
+	query := r.URL.Query()
+	opts := commitOptions{
+		FullMessage:   query.Get("fullMessage") == "true",
+		MaxBodyLength: defaultMaxBodyLength,
+		Anonymize:     parseAnonymize(r),
+	}
+	if value := query.Get("maxBodyLength"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("maxBodyLength must be a non-negative integer")
+		}
+		opts.MaxBodyLength = n
+	}
+	switch query.Get("humanDate") {
+	case "", "fuzzy":
+	case "exact":
+		opts.ExactHumanDate = true
+	default:
+		return opts, fmt.Errorf("humanDate must be fuzzy or exact")
+	}
+	return opts, nil
+}
+
+// truncateMessage keeps the subject line and at most maxBody characters of
+// the body.
+func truncateMessage(message string, maxBody int) (string, bool) {
+	subject := commitSubject(message)
+	body := []rune(commitBody(message))
+	if len(body) <= maxBody {
+		return message, false
+	}
+	if maxBody == 0 {
+		return subject, true
+	}
+	return subject + "\n\n" + string(body[:maxBody]) + "…", true
+}
+
+// emptyTreeHash is the well-known hash of a tree with no entries.
+var emptyTreeHash = plumbing.NewHash("4b825dc642cb6eb9a060e54bf8d69288fbee4904")
+
+// isEmptyCommit reports whether c leaves the tree exactly as its first parent
+// had it, as with `git commit --allow-empty` or a merge that brought in no
+// changes. A root commit is empty when its tree is.
+func isEmptyCommit(c *object.Commit) bool {
+	if c.NumParents() == 0 {
+		return c.TreeHash == emptyTreeHash
+	}
+	parent, err := c.Parent(0)
+	if err != nil {
+		return false
+	}
+	return c.TreeHash == parent.TreeHash
+}
+
+func newCommit(c *object.Commit, opts commitOptions) Commit {
+	commit := Commit{
+		Hash:      c.Hash.String(),
+		TreeHash:  c.TreeHash.String(),
+		Author:    c.Author.Name,
+		Email:     redactEmail(c.Author.Email),
+		Message:   redactText(c.Message),
+		Date:      c.Author.When.Format(time.RFC3339),
+		HumanDate: humanDate(c.Author.When, time.Now(), opts.ExactHumanDate),
+		Bot:       isBot(c.Author),
+		Empty:     isEmptyCommit(c),
+		// Trailers are parsed before truncation, which would drop them.
+		CommitTrailers: parseTrailers(c.Message).redacted(),
+	}
+	if opts.Anonymize {
+		commit.Author, commit.Email = pseudonym(c.Author.Name, c.Author.Email)
+		commit.Message = anonymizeMessage(commit.Message, c)
+		commit.CommitTrailers = parseTrailers(c.Message).anonymized()
+	}
+	if !opts.FullMessage {
+		commit.Message, commit.MessageTruncated = truncateMessage(commit.Message, opts.MaxBodyLength)
+	}
+	return commit
+}
+
+// setModifications attaches the commit's file changes and the metrics
+// derived from them.
+func (c *Commit) setModifications(modifications []FileModification) {
+	c.Modifications = modifications
+	additions, deletions := 0, 0
+	for _, mod := range modifications {
+		additions += mod.Additions
+		deletions += mod.Deletions
+	}
+	if additions > 0 {
+		ratio := float64(deletions) / float64(additions)
+		c.ChurnRatio = &ratio
+	}
+	// A commit that adds no lines is only renaming or removing files.
+	highRatio := additions == 0 || *c.ChurnRatio >= refactorMinChurnRatio
+	c.LikelyRefactor = highRatio && len(modifications) >= refactorMinFiles
+}
+
+// commitOrder is the walk order selected by the order query parameter.
+// go-git has neither author-date nor strict topological order, so those are
+// produced by sorting the committer-date walk.
+type commitOrder struct {
+	Name         string
+	ByAuthorDate bool
+	Topological  bool
+}
+
+const defaultCommitOrder = "committer-date"
+
+func parseCommitOrder(value string) (commitOrder, error) {
+	if value == "" {
+		value = defaultCommitOrder
+	}
+	switch value {
+	case "committer-date":
+		return commitOrder{Name: value}, nil
+	case "author-date":
+		return commitOrder{Name: value, ByAuthorDate: true}, nil
+	case "topological":
+		return commitOrder{Name: value, Topological: true}, nil
+	default:
+		return commitOrder{}, fmt.Errorf("order must be one of committer-date, author-date or topological")
+	}
+}
+
+// forEachCommit walks the log in the requested order. Author-date and
+// topological order have to load the whole history before the first
+// callback.
+func forEachCommit(ctx context.Context, repo *git.Repository, branch string, opts *git.LogOptions, order commitOrder, fn func(*object.Commit) error) error {
+	if opts == nil {
+		opts = &git.LogOptions{}
+	}
+	opts.Order = git.LogOrderCommitterTime
+
+	iter, err := commitLog(ctx, repo, branch, opts)
+	if err != nil {
+		return err
+	}
+	if !order.ByAuthorDate && !order.Topological {
+		return iter.ForEach(fn)
+	}
+
+	var commits []*object.Commit
+	err = iter.ForEach(func(c *object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if order.ByAuthorDate {
+		sort.SliceStable(commits, func(i, j int) bool {
+			return commits[i].Author.When.After(commits[j].Author.When)
+		})
+	} else {
+		commits = topoSort(commits)
+	}
+	for _, c := range commits {
+		if err := fn(c); err == storer.ErrStop {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// topoSort reorders commits so no parent appears before any of its children,
+// preferring the most recently committed commit whenever several are ready.
+// The input is expected in committer-date order.
+func topoSort(commits []*object.Commit) []*object.Commit {
+	index := make(map[plumbing.Hash]int, len(commits))
+	for i, c := range commits {
+		index[c.Hash] = i
+	}
+
+	children := make([]int, len(commits))
+	for _, c := range commits {
+		for _, parent := range c.ParentHashes {
+			if i, ok := index[parent]; ok {
+				children[i]++
+			}
+		}
+	}
+
+	ready := &indexHeap{}
+	for i, n := range children {
+		if n == 0 {
+			heap.Push(ready, i)
+		}
+	}
+
+	sorted := make([]*object.Commit, 0, len(commits))
+	for ready.Len() > 0 {
+		c := commits[heap.Pop(ready).(int)]
+		sorted = append(sorted, c)
+		for _, parent := range c.ParentHashes {
+			if i, ok := index[parent]; ok {
+				children[i]--
+				if children[i] == 0 {
+					heap.Push(ready, i)
+				}
+			}
+		}
+	}
+	return sorted
+}
+
+type indexHeap []int
+
+func (h indexHeap) Len() int            { return len(h) }
+func (h indexHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h indexHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *indexHeap) Push(x interface{}) { *h = append(*h, x.(int)) }
+func (h *indexHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+const defaultPageSize = 50
+
+type CommitPage struct {
+	// Commits is a []Commit, or with fields= only the selected fields of
+	// each.
+	Commits    any    `json:"commits"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// CommitsHandler returns the full commit list, or a page of it when limit or
+// after is given. after is the hash of the last commit the client has seen;
+// the page continues right after it in the same order and filters, so the
+// cursor is only meaningful when those parameters stay unchanged. Pages also
+// carry Link headers to the next and previous page, and every response has
+// X-Total-Count, which costs walking the rest of the history.
+func CommitsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	order, err := parseCommitOrder(query.Get("order"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	commitOpts, err := parseCommitOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fields, err := parseFields(r, Commit{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	after := query.Get("after")
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+	paginate := after != "" || limit > 0
+	if paginate && limit == 0 {
+		limit = defaultPageSize
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	commits := []Commit{}
+	cursorFound := after == ""
+	hasMore := false
+	total := 0
+	// before holds the last limit+1 hashes up to and including the cursor,
+	// the first of which is the cursor of the previous page.
+	var before []string
+	err = forEachCommit(r.Context(), repo, query.Get("branch"), filter.logOptions(), order, func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		// The walk goes on past the page, counting only, so the total
+		// can be reported.
+		total++
+		if !cursorFound {
+			if before = append(before, c.Hash.String()); len(before) > limit+1 {
+				before = before[1:]
+			}
+			cursorFound = c.Hash.String() == after
+			return nil
+		}
+		if paginate && len(commits) == limit {
+			hasMore = true
+			return nil
+		}
+		commits = append(commits, newCommit(c, commitOpts))
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+	if !cursorFound {
+		http.Error(w, "Cursor commit not found in history", http.StatusBadRequest)
+		return
+	}
+
+	if !paginate {
+		setPaginationHeaders(w, total, nil)
+		writeJSONFields(w, r, commits, fields)
+		return
+	}
+
+	masked, err := fields.apply(commits)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to select fields: %v", err), http.StatusInternalServerError)
+		return
+	}
+	page := CommitPage{Commits: masked}
+	var links []string
+	if hasMore {
+		page.NextCursor = commits[len(commits)-1].Hash
+		links = append(links, pageLink(r, "next", map[string]string{"after": page.NextCursor}))
+	}
+	if after != "" {
+		prev := ""
+		if len(before) > limit {
+			prev = before[0]
+		}
+		links = append(links, pageLink(r, "prev", map[string]string{"after": prev}))
+	}
+	setPaginationHeaders(w, total, links)
+	writeJSON(w, r, page)
+}
+
+// CountHandler returns just the number of commits CommitsHandler would list
+// for the same branch and filters, without building any commit payloads.
+func CountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, r.URL.Query().Get("branch"), filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	count := 0
+	err = iter.ForEach(func(c *object.Commit) error {
+		if filter.matches(c) {
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, count)
+}
+
+const maxBatchHashes = 1000
+
+type CommitBatchRequest struct {
+	RepoID string   `json:"repoId"`
+	Hashes []string `json:"hashes"`
+}
+
+type CommitBatchResult struct {
+	Hash   string  `json:"hash"`
+	Commit *Commit `json:"commit,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// CommitsBatchHandler looks up a known set of commits directly instead of
+// walking the log. Results are in request order and a hash that cannot be
+// resolved only fails its own entry.
+func CommitsBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	commitOpts, err := parseCommitOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req CommitBatchRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if len(req.Hashes) == 0 {
+		http.Error(w, "hashes is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Hashes) > maxBatchHashes {
+		http.Error(w, fmt.Sprintf("at most %d hashes are allowed per batch", maxBatchHashes), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := openRequestedRepo(w, req.RepoID)
+	if !ok {
+		return
+	}
+
+	results := make([]CommitBatchResult, 0, len(req.Hashes))
+	for _, hash := range req.Hashes {
+		result := CommitBatchResult{Hash: hash}
+		if !plumbing.IsHash(hash) {
+			result.Error = "invalid commit hash"
+			results = append(results, result)
+			continue
+		}
+
+		c, err := repo.CommitObject(plumbing.NewHash(hash))
+		if err != nil {
+			result.Error = errCommitNotFound.Error()
+			results = append(results, result)
+			continue
+		}
+
+		commit := newCommit(c, commitOpts)
+		modifications, err := commitModifications(c)
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to get stats: %v", err)
+		}
+		commit.setModifications(modifications)
+		result.Commit = &commit
+		results = append(results, result)
+	}
+
+	writeJSON(w, r, results)
+}
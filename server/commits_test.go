@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCommitsHandler(t *testing.T) {
+	f := newFixtureRepo(t,
+		fixtureCommit{Message: "Initial commit\n", Files: map[string]string{"README.md": "hello\n"}},
+		fixtureCommit{Message: "Add docs\n", Name: "Bob", Email: "bob@example.com", Files: map[string]string{"docs/guide.md": "guide\n"}},
+		fixtureCommit{Message: "Update readme\n", Files: map[string]string{"README.md": "hello\nworld\n"}},
+	)
+
+	tests := []struct {
+		name       string
+		method     string
+		query      url.Values
+		wantStatus int
+		// wantHashes are indexes into f.Hashes, in response order.
+		wantHashes []int
+		wantTotal  string
+		wantCursor bool
+	}{
+		{name: "all commits newest first", query: url.Values{}, wantStatus: http.StatusOK, wantHashes: []int{2, 1, 0}, wantTotal: "3"},
+		{name: "author filter", query: url.Values{"author": {"bob"}}, wantStatus: http.StatusOK, wantHashes: []int{1}, wantTotal: "1"},
+		{name: "path filter", query: url.Values{"path": {"README.md"}}, wantStatus: http.StatusOK, wantHashes: []int{2, 0}, wantTotal: "2"},
+		{name: "first page", query: url.Values{"limit": {"2"}}, wantStatus: http.StatusOK, wantHashes: []int{2, 1}, wantTotal: "3", wantCursor: true},
+		{name: "page after cursor", query: url.Values{"limit": {"2"}, "after": {f.Hashes[1].String()}}, wantStatus: http.StatusOK, wantHashes: []int{0}, wantTotal: "3"},
+		{name: "invalid limit", query: url.Values{"limit": {"0"}}, wantStatus: http.StatusBadRequest},
+		{name: "unknown branch", query: url.Values{"branch": {"nope"}}, wantStatus: http.StatusNotFound},
+		{name: "missing repoId", query: url.Values{"repoId": {""}}, wantStatus: http.StatusBadRequest},
+		{name: "unknown repoId", query: url.Values{"repoId": {"missing-00000000"}}, wantStatus: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodPost, query: url.Values{}, wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := tt.query["repoId"]; !ok {
+				tt.query.Set("repoId", f.ID)
+			}
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			w := serve(CommitsHandler, method, "/commits?"+tt.query.Encode(), nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get(totalCountHeader); got != tt.wantTotal {
+				t.Errorf("%s = %q, want %q", totalCountHeader, got, tt.wantTotal)
+			}
+
+			var commits []Commit
+			var page struct {
+				Commits    []Commit `json:"commits"`
+				NextCursor string   `json:"nextCursor"`
+			}
+			paginated := tt.query.Has("limit") || tt.query.Has("after")
+			if paginated {
+				if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+					t.Fatalf("decode page: %v", err)
+				}
+				commits = page.Commits
+			} else if err := json.Unmarshal(w.Body.Bytes(), &commits); err != nil {
+				t.Fatalf("decode commits: %v", err)
+			}
+
+			if len(commits) != len(tt.wantHashes) {
+				t.Fatalf("got %d commits, want %d", len(commits), len(tt.wantHashes))
+			}
+			for i, want := range tt.wantHashes {
+				if commits[i].Hash != f.Hashes[want].String() {
+					t.Errorf("commit %d = %s, want %s", i, commits[i].Hash, f.Hashes[want])
+				}
+			}
+			if paginated && (page.NextCursor != "") != tt.wantCursor {
+				t.Errorf("nextCursor = %q, want set: %t", page.NextCursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestEmptyCommits(t *testing.T) {
+	f := newFixtureRepo(t,
+		fixtureCommit{Message: "Root without files\n"},
+		fixtureCommit{Message: "Add readme\n", Files: map[string]string{"README.md": "hello\n"}},
+		fixtureCommit{Message: "Trigger CI\n"},
+		fixtureCommit{Message: " \n\t\n"},
+		fixtureCommit{Message: "Reindent readme\n", Files: map[string]string{"README.md": "hello \n"}},
+	)
+	wantEmpty := map[string]bool{
+		f.Hashes[0].String(): true,
+		f.Hashes[1].String(): false,
+		f.Hashes[2].String(): true,
+		f.Hashes[3].String(): true,
+		f.Hashes[4].String(): false,
+	}
+
+	tests := []struct {
+		name         string
+		excludeEmpty bool
+		wantCount    int
+	}{
+		{name: "flagged", wantCount: 5},
+		{name: "excluded", excludeEmpty: true, wantCount: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"repoId": {f.ID}}
+			if tt.excludeEmpty {
+				query.Set("excludeEmpty", "true")
+			}
+			w := serve(CommitsHandler, http.MethodGet, "/commits?"+query.Encode(), nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			var commits []Commit
+			if err := json.Unmarshal(w.Body.Bytes(), &commits); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(commits) != tt.wantCount {
+				t.Fatalf("got %d commits, want %d", len(commits), tt.wantCount)
+			}
+			for _, c := range commits {
+				if c.Empty != wantEmpty[c.Hash] {
+					t.Errorf("commit %q empty = %t, want %t", c.Message, c.Empty, wantEmpty[c.Hash])
+				}
+				if tt.excludeEmpty && c.Empty {
+					t.Errorf("empty commit %q not excluded", c.Message)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+func envString(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func envInt(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Ignoring invalid integer setting", "name", name, "value", value)
+		return defaultValue
+	}
+	return n
+}
+
+func envFloat(name string, defaultValue float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("Ignoring invalid number setting", "name", name, "value", value)
+		return defaultValue
+	}
+	return f
+}
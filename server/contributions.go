@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const (
+	defaultTimeseriesTop = 10
+	othersSeries         = "others"
+)
+
+type LineStats struct {
+	Additions int `json:"additions"`
+	Deletions int `json:"deletions"`
+}
+
+type ContributionMonth struct {
+	Month   string               `json:"month"`
+	Authors map[string]LineStats `json:"authors"`
+}
+
+type ContributionsTimeseries struct {
+	Top    int                 `json:"top"`
+	Series []string            `json:"series"`
+	Months []ContributionMonth `json:"months"`
+}
+
+// ContributionsTimeseriesHandler returns, per calendar month, the lines each
+// contributor added and deleted, ready for a stacked area chart. Authors are
+// mailmap-resolved; only the top contributors by additions get their own
+// series and everyone else is summed into "others". Months without commits
+// are included with no authors so the series is continuous.
+func ContributionsTimeseriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	top := defaultTimeseriesTop
+	if value := r.URL.Query().Get("top"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "top must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if refuseShallow(w, r, repo) {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, r.URL.Query().Get("branch"), filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	mm := loadMailmap(repo)
+	anonymize := parseAnonymize(r)
+	labels := authorLabels{}
+	perMonth := map[time.Time]map[string]LineStats{}
+	totals := map[string]int{}
+	err = forEachCommitStats(repoID, iter, filter.matches, func(c *object.Commit, modifications []FileModification) error {
+		var stats LineStats
+		for _, mod := range modifications {
+			if filter.includesFile(mod.File) {
+				stats.Additions += mod.Additions
+				stats.Deletions += mod.Deletions
+			}
+		}
+
+		author := labels.label(mm.resolve(c.Author.Name, c.Author.Email), anonymize)
+		when := c.Author.When.UTC()
+		month := time.Date(when.Year(), when.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if perMonth[month] == nil {
+			perMonth[month] = map[string]LineStats{}
+		}
+		current := perMonth[month][author]
+		current.Additions += stats.Additions
+		current.Deletions += stats.Deletions
+		perMonth[month][author] = current
+		totals[author] += stats.Additions
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	authors := make([]string, 0, len(totals))
+	for author := range totals {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if totals[authors[i]] != totals[authors[j]] {
+			return totals[authors[i]] > totals[authors[j]]
+		}
+		return authors[i] < authors[j]
+	})
+
+	resp := ContributionsTimeseries{Top: top, Series: []string{}, Months: []ContributionMonth{}}
+	series := map[string]bool{}
+	for i, author := range authors {
+		if i == top {
+			resp.Series = append(resp.Series, othersSeries)
+			break
+		}
+		series[author] = true
+		resp.Series = append(resp.Series, author)
+	}
+
+	var first, last time.Time
+	for month := range perMonth {
+		if first.IsZero() || month.Before(first) {
+			first = month
+		}
+		if month.After(last) {
+			last = month
+		}
+	}
+	for month := first; len(perMonth) > 0 && !month.After(last); month = month.AddDate(0, 1, 0) {
+		entry := ContributionMonth{Month: month.Format("2006-01"), Authors: map[string]LineStats{}}
+		for author, stats := range perMonth[month] {
+			key := author
+			if !series[author] {
+				key = othersSeries
+			}
+			current := entry.Authors[key]
+			current.Additions += stats.Additions
+			current.Deletions += stats.Deletions
+			entry.Authors[key] = current
+		}
+		resp.Months = append(resp.Months, entry)
+	}
+
+	writeJSON(w, r, resp)
+}
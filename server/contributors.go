@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type ContributorCount struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	// CanonicalID is the key the author is counted under, after the
+	// mailmap and IDENTITY_RULES. It is omitted with anonymize.
+	CanonicalID string `json:"canonicalId,omitempty"`
+	Commits     int    `json:"commits"`
+}
+
+// authorCounter tallies commits per author, keyed by canonicalID so aliases
+// of the same person are counted once.
+type authorCounter struct {
+	mailmap *mailmap
+	counts  map[string]*ContributorCount
+	// anonymize lists the authors under pseudonyms. Counts are kept under
+	// the real identities either way so counters can still be merged.
+	anonymize bool
+}
+
+func newAuthorCounter(mm *mailmap) *authorCounter {
+	return &authorCounter{mailmap: mm, counts: map[string]*ContributorCount{}}
+}
+
+func (a *authorCounter) add(sig object.Signature) {
+	author, key := a.resolve(sig)
+	if a.counts[key] == nil {
+		a.counts[key] = &ContributorCount{Name: author.Name, Email: author.Email, CanonicalID: key}
+	}
+	a.counts[key].Commits++
+}
+
+// resolve applies the mailmap and returns the key the author is counted
+// under, from canonicalID.
+func (a *authorCounter) resolve(sig object.Signature) (identity, string) {
+	author := a.mailmap.resolve(sig.Name, sig.Email)
+	return author, canonicalID(author)
+}
+
+// addCommit counts c for its author and, with creditCoauthors, once more for
+// each distinct Co-authored-by trailer that names someone else.
+func (a *authorCounter) addCommit(c *object.Commit, creditCoauthors bool) {
+	a.add(c.Author)
+	if !creditCoauthors {
+		return
+	}
+	_, authorKey := a.resolve(c.Author)
+	seen := map[string]bool{authorKey: true}
+	for _, sig := range coAuthors(c) {
+		if _, key := a.resolve(sig); !seen[key] {
+			seen[key] = true
+			a.add(sig)
+		}
+	}
+}
+
+// merge adds other's counts to a. Authors are matched by the key each
+// counter resolved them to, so aliases mapped to the same email by different
+// repositories' mailmaps are counted once.
+func (a *authorCounter) merge(other *authorCounter) {
+	for key, count := range other.counts {
+		if a.counts[key] == nil {
+			merged := *count
+			a.counts[key] = &merged
+			continue
+		}
+		a.counts[key].Commits += count.Commits
+	}
+}
+
+func (a *authorCounter) len() int {
+	return len(a.counts)
+}
+
+// listed is count as shown in a response, pseudonymised or redacted.
+func (a *authorCounter) listed(count *ContributorCount) ContributorCount {
+	listed := *count
+	listed.CanonicalID = listedCanonicalID(count.CanonicalID, a.anonymize)
+	if a.anonymize {
+		listed.Name, listed.Email = pseudonym(count.Name, count.Email)
+	} else {
+		listed.Email = redactEmail(count.Email)
+	}
+	return listed
+}
+
+// sorted returns the authors by commit count, most active first.
+func (a *authorCounter) sorted() []ContributorCount {
+	contributors := make([]ContributorCount, 0, len(a.counts))
+	for _, count := range a.counts {
+		contributors = append(contributors, a.listed(count))
+	}
+	sort.Slice(contributors, func(i, j int) bool {
+		if contributors[i].Commits != contributors[j].Commits {
+			return contributors[i].Commits > contributors[j].Commits
+		}
+		return contributors[i].Name < contributors[j].Name
+	})
+	return contributors
+}
+
+// regular returns the authors with at least minCommits commits, most active
+// first, and how many were left out for having fewer.
+func (a *authorCounter) regular(minCommits int) ([]ContributorCount, int) {
+	all := a.sorted()
+	kept := all[:0]
+	for _, count := range all {
+		if count.Commits >= minCommits {
+			kept = append(kept, count)
+		}
+	}
+	return kept, len(all) - len(kept)
+}
+
+type ActiveContributorsResponse struct {
+	Days         int                `json:"days"`
+	Since        string             `json:"since"`
+	TotalCommits int                `json:"totalCommits"`
+	Contributors []ContributorCount `json:"contributors"`
+	// ExcludedContributors counts the authors dropped by minCommits.
+	ExcludedContributors int `json:"excludedContributors"`
+}
+
+func parseDays(r *http.Request, defaultDays int) (int, error) {
+	value := r.URL.Query().Get("days")
+	if value == "" {
+		return defaultDays, nil
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days <= 0 {
+		return 0, fmt.Errorf("days must be a positive integer")
+	}
+	return days, nil
+}
+
+// parseMinCommits reads minCommits, the number of commits an author needs to
+// be listed. The default of 1 lists everyone.
+func parseMinCommits(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("minCommits")
+	if value == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("minCommits must be a positive integer")
+	}
+	return n, nil
+}
+
+func ActiveContributorsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days, err := parseDays(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	creditCoauthors := r.URL.Query().Get("creditCoauthors") == "true"
+	minCommits, err := parseMinCommits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	since := time.Now().AddDate(0, 0, -days)
+	opts := filter.logOptions()
+	opts.Since = &since
+	iter, err := commitLog(r.Context(), repo, "", opts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	authors := newAuthorCounter(loadMailmap(repo))
+	authors.anonymize = parseAnonymize(r)
+	resp := ActiveContributorsResponse{
+		Days:  days,
+		Since: since.Format(time.RFC3339),
+	}
+
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		if c.Author.When.Before(since) {
+			return nil
+		}
+		resp.TotalCommits++
+		authors.addCommit(c, creditCoauthors)
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp.Contributors, resp.ExcludedContributors = authors.regular(minCommits)
+
+	writeJSON(w, r, resp)
+}
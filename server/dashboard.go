@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const (
+	dashboardRecentCommits = 20
+	dashboardTopN          = 10
+)
+
+type Dashboard struct {
+	RepoID               string             `json:"repoId"`
+	CurrentBranch        string             `json:"currentBranch,omitempty"`
+	DetachedHead         bool               `json:"detachedHead"`
+	Shallow              bool               `json:"shallow"`
+	ShallowBoundary      []string           `json:"shallowBoundary,omitempty"`
+	TotalCommits         int                `json:"totalCommits"`
+	Contributors         int                `json:"contributors"`
+	ExcludedContributors int                `json:"excludedContributors"`
+	Tags                 int                `json:"tags"`
+	PrimaryLanguage      string             `json:"primaryLanguage"`
+	Branches             []string           `json:"branches"`
+	RecentCommits        []Commit           `json:"recentCommits"`
+	TopContributors      []ContributorCount `json:"topContributors"`
+	Hotspots             []*FileChurn       `json:"hotspots"`
+}
+
+// DashboardHandler is a convenience aggregation for the initial page load.
+// It computes everything from a single log walk; the full data behind each
+// section still comes from /commits, /files and /summary.
+func DashboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := dashboardRecentCommits
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	commitOpts, err := parseCommitOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	minCommits, err := parseMinCommits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	dashboard := Dashboard{RepoID: repoID, RecentCommits: []Commit{}}
+
+	dashboard.ShallowBoundary = shallowBoundary(repo)
+	dashboard.Shallow = len(dashboard.ShallowBoundary) > 0
+	dashboard.CurrentBranch, dashboard.DetachedHead, err = headState(repo)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get HEAD reference: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	dashboard.Branches, err = getBranches(repo)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get branches: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := listTags(repo)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get tags: %v", err), http.StatusInternalServerError)
+		return
+	}
+	dashboard.Tags = len(tags)
+
+	if languages, err := headLanguages(repo); err == nil {
+		dashboard.PrimaryLanguage = primaryLanguage(languages)
+	}
+
+	iter, err := commitLog(r.Context(), repo, "", filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	authors := newAuthorCounter(loadMailmap(repo))
+	authors.anonymize = commitOpts.Anonymize
+	files := churnCounter{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+
+		dashboard.TotalCommits++
+		authors.add(c.Author)
+		if len(dashboard.RecentCommits) < limit {
+			dashboard.RecentCommits = append(dashboard.RecentCommits, newCommit(c, commitOpts))
+		}
+
+		modifications, err := commitModifications(c)
+		if err != nil {
+			return err
+		}
+		for _, mod := range modifications {
+			if filter.includesFile(mod.File) {
+				files.add(mod, c.Author.When)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	dashboard.TopContributors, dashboard.ExcludedContributors = authors.regular(minCommits)
+	dashboard.Contributors = len(dashboard.TopContributors)
+	if len(dashboard.TopContributors) > dashboardTopN {
+		dashboard.TopContributors = dashboard.TopContributors[:dashboardTopN]
+	}
+	dashboard.Hotspots = files.sorted(fileChurnSorts["churn"], dashboardTopN)
+
+	writeJSON(w, r, dashboard)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type DepthBucket struct {
+	// Depth is the number of directories above the file; 0 is the
+	// repository root.
+	Depth int `json:"depth"`
+	// Changes counts file modifications, so a commit touching three files
+	// at one depth adds three.
+	Changes   int `json:"changes"`
+	Commits   int `json:"commits"`
+	Files     int `json:"files"`
+	Additions int `json:"additions"`
+	Deletions int `json:"deletions"`
+	Churn     int `json:"churn"`
+}
+
+type DepthDistribution struct {
+	// Buckets runs from the root to the deepest changed file, including
+	// depths no change reached.
+	Buckets []DepthBucket `json:"buckets"`
+	// MeanDepth is the depth of the average changed line, weighting each
+	// file modification by its churn.
+	MeanDepth float64 `json:"meanDepth"`
+}
+
+func pathDepth(file string) int {
+	return strings.Count(file, "/")
+}
+
+// DepthDistributionHandler buckets the file modifications across history by
+// the directory depth of the file, showing whether churn sits in deeply
+// nested modules or near the top level. Vendored and build paths are
+// skipped, as in the churn breakdowns.
+func DepthDistributionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, r.URL.Query().Get("branch"), filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	var buckets []DepthBucket
+	files := map[string]bool{}
+	err = forEachCommitStats(repoID, iter, filter.matches, func(c *object.Commit, modifications []FileModification) error {
+		touched := map[int]bool{}
+		for _, mod := range modifications {
+			if isIgnoredPath(mod.File) || !filter.includesFile(mod.File) {
+				continue
+			}
+			depth := pathDepth(mod.File)
+			for len(buckets) <= depth {
+				buckets = append(buckets, DepthBucket{Depth: len(buckets)})
+			}
+			bucket := &buckets[depth]
+			bucket.Changes++
+			bucket.Additions += mod.Additions
+			bucket.Deletions += mod.Deletions
+			bucket.Churn += mod.Additions + mod.Deletions
+			if !files[mod.File] {
+				files[mod.File] = true
+				bucket.Files++
+			}
+			touched[depth] = true
+		}
+		for depth := range touched {
+			buckets[depth].Commits++
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := DepthDistribution{Buckets: buckets}
+	if resp.Buckets == nil {
+		resp.Buckets = []DepthBucket{}
+	}
+	var churn, weighted int
+	for _, bucket := range resp.Buckets {
+		churn += bucket.Churn
+		weighted += bucket.Depth * bucket.Churn
+	}
+	if churn > 0 {
+		resp.MeanDepth = float64(weighted) / float64(churn)
+	}
+
+	writeJSON(w, r, resp)
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	linediff "github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	// patchTruncatedHeader is set on patch downloads that were cut short.
+	patchTruncatedHeader = "X-Patch-Truncated"
+	// patchTranscodedHeader names the sourceEncoding that lines of a patch
+	// download which were not valid UTF-8 were decoded from.
+	patchTranscodedHeader = "X-Patch-Transcoded-From"
+)
+
+var (
+	// diffTimeout bounds how long one request may spend diffing.
+	diffTimeout = time.Duration(envInt("DIFF_TIMEOUT_SECONDS", 30)) * time.Second
+	// maxPatchBytes caps the patch text one response carries.
+	maxPatchBytes = envInt("DIFF_MAX_PATCH_BYTES", 10<<20)
+)
+
+type RangeDiffResponse struct {
+	From           string             `json:"from"`
+	To             string             `json:"to"`
+	Files          []FileModification `json:"files"`
+	TotalAdditions int                `json:"totalAdditions"`
+	TotalDeletions int                `json:"totalDeletions"`
+	Patch          string             `json:"patch,omitempty"`
+	// PatchEncoding is "base64" when the patch is not valid UTF-8 and no
+	// sourceEncoding was given, so Patch holds its base64-encoded bytes.
+	PatchEncoding string `json:"patchEncoding,omitempty"`
+	// TranscodedFrom names the sourceEncoding that lines of the patch which
+	// were not valid UTF-8 were decoded from.
+	TranscodedFrom string `json:"transcodedFrom,omitempty"`
+	// Truncated is set when DIFF_TIMEOUT_SECONDS or DIFF_MAX_PATCH_BYTES
+	// stopped the diff early; Files and Patch then cover only the files
+	// diffed until then.
+	Truncated bool `json:"truncated"`
+}
+
+// boundedPatch is a diff rendered one file at a time, which may stop before
+// the last file.
+type boundedPatch struct {
+	stats     object.FileStats
+	text      strings.Builder
+	truncated bool
+}
+
+// diffChanges diffs changes file by file until ctx is done or, with
+// withText, the patch text would grow past maxPatchBytes. Only whole files
+// are included, so a truncated patch still applies cleanly up to where it
+// stops.
+func diffChanges(ctx context.Context, changes object.Changes, withText bool) (*boundedPatch, error) {
+	result := &boundedPatch{}
+	for _, change := range changes {
+		patch, err := patchWithin(ctx, change)
+		if ctx.Err() != nil {
+			result.truncated = true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if withText {
+			text := patch.String()
+			if result.text.Len()+len(text) > maxPatchBytes {
+				result.truncated = true
+				break
+			}
+			result.text.WriteString(text)
+		}
+		result.stats = append(result.stats, patch.Stats()...)
+	}
+	return result, nil
+}
+
+// patchWithin diffs one change the way Change.PatchContext does, except
+// that the line diff itself is bounded by ctx's deadline. go-git only
+// checks the context between files and runs each diff for up to an hour,
+// so a single pathological file could otherwise keep a CPU busy long after
+// the request gave up on it.
+func patchWithin(ctx context.Context, change *object.Change) (*filePatch, error) {
+	from, to, err := change.Files()
+	if err != nil {
+		return nil, err
+	}
+	fromContent, fromBinary, err := fileContent(from)
+	if err != nil {
+		return nil, err
+	}
+	toContent, toBinary, err := fileContent(to)
+	if err != nil {
+		return nil, err
+	}
+	patch := &filePatch{from: change.From, to: change.To}
+	if fromBinary || toBinary {
+		return patch, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	timeout := time.Hour
+	if deadline, ok := ctx.Deadline(); ok {
+		// diffmatchpatch reads a timeout of 0 or less as none at all, so a
+		// deadline that has just passed waits for ctx to catch up instead.
+		if timeout = time.Until(deadline); timeout <= 0 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+	}
+	for _, d := range linediff.DoWithTimeout(fromContent, toContent, timeout) {
+		op := fdiff.Equal
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			op = fdiff.Delete
+		case diffmatchpatch.DiffInsert:
+			op = fdiff.Add
+		}
+		patch.chunks = append(patch.chunks, patchChunk{d.Text, op})
+	}
+	// Past the deadline the diff above returns a coarse delete+insert of
+	// whatever it had not compared yet, which is not worth showing.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+func fileContent(f *object.File) (content string, binary bool, err error) {
+	if f == nil {
+		return "", false, nil
+	}
+	if binary, err = f.IsBinary(); err != nil || binary {
+		return "", binary, err
+	}
+	content, err = f.Contents()
+	return content, false, err
+}
+
+// filePatch is the diff of one change. It renders and counts exactly like
+// the single-file object.Patch go-git would have built.
+type filePatch struct {
+	chunks   []fdiff.Chunk
+	from, to object.ChangeEntry
+}
+
+func (p *filePatch) Files() (from, to fdiff.File) {
+	if p.from.TreeEntry.Mode.IsFile() {
+		from = patchFile{p.from}
+	}
+	if p.to.TreeEntry.Mode.IsFile() {
+		to = patchFile{p.to}
+	}
+	return from, to
+}
+
+func (p *filePatch) IsBinary() bool        { return len(p.chunks) == 0 }
+func (p *filePatch) Chunks() []fdiff.Chunk { return p.chunks }
+
+func (p *filePatch) FilePatches() []fdiff.FilePatch { return []fdiff.FilePatch{p} }
+func (p *filePatch) Message() string                { return "" }
+
+func (p *filePatch) String() string {
+	var buf strings.Builder
+	if err := fdiff.NewUnifiedEncoder(&buf, fdiff.DefaultContextLines).Encode(p); err != nil {
+		return fmt.Sprintf("malformed patch: %s", err)
+	}
+	return buf.String()
+}
+
+// Stats counts added and deleted lines; binary files and submodule updates
+// have no chunks and no stats.
+func (p *filePatch) Stats() object.FileStats {
+	if len(p.chunks) == 0 {
+		return nil
+	}
+	var stat object.FileStat
+	from, to := p.Files()
+	switch {
+	case from == nil:
+		stat.Name = to.Path()
+	case to == nil, from.Path() == to.Path():
+		stat.Name = from.Path()
+	default:
+		stat.Name = fmt.Sprintf("%s => %s", from.Path(), to.Path())
+	}
+	for _, chunk := range p.chunks {
+		lines := countLines(chunk.Content())
+		switch chunk.Type() {
+		case fdiff.Add:
+			stat.Addition += lines
+		case fdiff.Delete:
+			stat.Deletion += lines
+		}
+	}
+	return object.FileStats{stat}
+}
+
+// countLines counts s's lines, including a last one without a newline.
+func countLines(s string) int {
+	n := strings.Count(s, "\n")
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		n++
+	}
+	return n
+}
+
+type patchFile struct {
+	entry object.ChangeEntry
+}
+
+func (f patchFile) Hash() plumbing.Hash     { return f.entry.TreeEntry.Hash }
+func (f patchFile) Mode() filemode.FileMode { return f.entry.TreeEntry.Mode }
+func (f patchFile) Path() string            { return f.entry.Name }
+
+type patchChunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c patchChunk) Content() string       { return c.content }
+func (c patchChunk) Type() fdiff.Operation { return c.op }
+
+func treeDiff(ctx context.Context, from, to *object.Commit, withText bool) (*boundedPatch, error) {
+	fromTree, err := from.Tree()
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := to.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := fromTree.DiffContext(ctx, toTree)
+	if ctx.Err() != nil {
+		return &boundedPatch{truncated: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return diffChanges(ctx, changes, withText)
+}
+
+func RangeDiffHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	fromRev, toRev := query.Get("from"), query.Get("to")
+	if fromRev == "" || toRev == "" {
+		http.Error(w, "from and to are required", http.StatusBadRequest)
+		return
+	}
+
+	// Per-file stats are always returned; the patch text is opt-in, so
+	// statOnly=true only exists to make the `git diff --stat` intent explicit
+	// and cannot be combined with patch=true.
+	statOnly := query.Get("statOnly") == "true"
+	includePatch := query.Get("patch") == "true"
+	if statOnly && includePatch {
+		http.Error(w, "statOnly and patch cannot both be true", http.StatusBadRequest)
+		return
+	}
+	decode, err := parseSourceEncoding(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	from, err := resolveCommit(repo, fromRev)
+	if err != nil {
+		http.Error(w, revisionErrorMessage(fromRev, err), logErrorStatus(err))
+		return
+	}
+	to, err := resolveCommit(repo, toRev)
+	if err != nil {
+		http.Error(w, revisionErrorMessage(toRev, err), logErrorStatus(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), diffTimeout)
+	defer cancel()
+	patch, err := treeDiff(ctx, from, to, includePatch)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to diff commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := RangeDiffResponse{
+		From:      from.Hash.String(),
+		To:        to.Hash.String(),
+		Files:     []FileModification{},
+		Truncated: patch.truncated,
+	}
+	for _, stat := range patch.stats {
+		resp.Files = append(resp.Files, FileModification{
+			File:      stat.Name,
+			Additions: stat.Addition,
+			Deletions: stat.Deletion,
+		})
+		resp.TotalAdditions += stat.Addition
+		resp.TotalDeletions += stat.Deletion
+	}
+	if includePatch {
+		var transcoded bool
+		resp.Patch, resp.PatchEncoding, transcoded = jsonSafeText(patch.text.String(), decode)
+		if transcoded {
+			resp.TranscodedFrom = strings.ToLower(query.Get("sourceEncoding"))
+		}
+	}
+
+	writeJSON(w, r, resp)
+}
+
+var patchFilenameUnsafe = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// patchFilename mirrors git format-patch naming: 0001- followed by the
+// subject with runs of other characters collapsed to dashes.
+func patchFilename(subject string) string {
+	name := strings.Trim(patchFilenameUnsafe.ReplaceAllString(subject, "-"), "-")
+	if len(name) > 52 {
+		name = strings.TrimRight(name[:52], "-")
+	}
+	if name == "" {
+		name = "patch"
+	}
+	return "0001-" + name + ".patch"
+}
+
+// commitPatch returns the changes c introduced relative to its first parent,
+// or relative to an empty tree for a root commit.
+func commitPatch(ctx context.Context, c *object.Commit) (*boundedPatch, error) {
+	if c.NumParents() == 0 {
+		tree, err := c.Tree()
+		if err != nil {
+			return nil, err
+		}
+		changes, err := object.DiffTreeContext(ctx, nil, tree)
+		if ctx.Err() != nil {
+			return &boundedPatch{truncated: true}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return diffChanges(ctx, changes, true)
+	}
+	parent, err := c.Parent(0)
+	if err != nil {
+		return nil, err
+	}
+	return treeDiff(ctx, parent, c, true)
+}
+
+// wrapBase64 breaks base64 text into 76-column lines, as MIME requires.
+// Each line is a whole number of 4-byte groups, so it decodes on its own.
+func wrapBase64(text string) string {
+	var b strings.Builder
+	for len(text) > 76 {
+		b.WriteString(text[:76])
+		b.WriteString("\n")
+		text = text[76:]
+	}
+	b.WriteString(text)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// CommitPatchHandler serves a commit as a git format-patch style mbox file
+// that `git am` can apply. Merge commits are rendered as their diff against
+// the first parent, the same as `git format-patch -1 --first-parent`. A
+// patch cut short by the diff limits is still served, with the files that
+// fit, and flagged with X-Patch-Truncated. Text that is not valid UTF-8 is
+// transcoded when sourceEncoding is given and otherwise sent base64-encoded
+// under MIME headers, the same choice RangeDiffHandler makes for JSON.
+func CommitPatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rev := r.URL.Query().Get("hash")
+	if rev == "" {
+		http.Error(w, "hash is required", http.StatusBadRequest)
+		return
+	}
+	decode, err := parseSourceEncoding(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	c, err := resolveCommit(repo, rev)
+	if err != nil {
+		http.Error(w, revisionErrorMessage(rev, err), logErrorStatus(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), diffTimeout)
+	defer cancel()
+	patch, err := commitPatch(ctx, c)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to diff commit: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	message := redactText(c.Message)
+	name, email := c.Author.Name, redactEmail(c.Author.Email)
+	if parseAnonymize(r) {
+		message = anonymizeMessage(message, c)
+		name, email = pseudonym(c.Author.Name, c.Author.Email)
+	}
+	subject := commitSubject(message)
+	var body strings.Builder
+	if text := commitBody(message); text != "" {
+		fmt.Fprintf(&body, "%s\n", text)
+	}
+	body.WriteString("---\n")
+	for _, stat := range patch.stats {
+		fmt.Fprintf(&body, " %s | %d %s%s\n", stat.Name, stat.Addition+stat.Deletion,
+			strings.Repeat("+", min(stat.Addition, 40)), strings.Repeat("-", min(stat.Deletion, 40)))
+	}
+	body.WriteString("\n")
+	body.WriteString(patch.text.String())
+	body.WriteString("-- \ninsightsRepo\n\n")
+	text, encoding, transcoded := jsonSafeText(body.String(), decode)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From %s Mon Sep 17 00:00:00 2001\n", c.Hash)
+	fmt.Fprintf(&b, "From: %s <%s>\n", name, email)
+	fmt.Fprintf(&b, "Date: %s\n", c.Author.When.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Subject: [PATCH] %s\n", subject)
+	if encoding == "base64" {
+		// The bytes are not UTF-8 and there is no sourceEncoding to read
+		// them with, so they go out untouched as a base64 MIME body, which
+		// git am decodes before applying.
+		b.WriteString("MIME-Version: 1.0\nContent-Type: text/plain\nContent-Transfer-Encoding: base64\n")
+		text = wrapBase64(text)
+	}
+	b.WriteString("\n")
+	b.WriteString(text)
+
+	if patch.truncated {
+		w.Header().Set(patchTruncatedHeader, "true")
+	}
+	if transcoded {
+		w.Header().Set(patchTranscodedHeader, strings.ToLower(r.URL.Query().Get("sourceEncoding")))
+	}
+	w.Header().Set("Content-Type", "text/x-patch; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", patchFilename(subject)))
+	io.WriteString(w, b.String())
+}
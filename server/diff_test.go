@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPatchWithinMatchesGoGit(t *testing.T) {
+	f := newFixtureRepo(t,
+		fixtureCommit{Message: "initial", Files: map[string]string{
+			"a.txt":   "one\ntwo\nthree\n",
+			"gone.md": "bye\n",
+			"bin.dat": "\x00\x01\x02",
+		}},
+		fixtureCommit{Message: "change", Files: map[string]string{
+			"a.txt":   "one\n2\nthree\nfour",
+			"new.txt": "fresh\n",
+			"bin.dat": "\x00\x01\x03",
+		}, Remove: []string{"gone.md"}},
+	)
+	from, err := f.Repo.CommitObject(f.Hashes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := f.Repo.CommitObject(f.Hashes[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromTree, _ := from.Tree()
+	toTree, _ := to.Tree()
+	changes, err := fromTree.Diff(toTree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 4 {
+		t.Fatalf("got %d changes, want 4", len(changes))
+	}
+
+	for _, change := range changes {
+		want, err := change.Patch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := patchWithin(context.Background(), change)
+		if err != nil {
+			t.Fatalf("%s: %v", change, err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("%s: patch\n%s\nwant\n%s", change, got, want)
+		}
+		if !reflect.DeepEqual(got.Stats(), want.Stats()) {
+			t.Errorf("%s: stats %v, want %v", change, got.Stats(), want.Stats())
+		}
+	}
+}
+
+func TestPatchWithinStopsAtDeadline(t *testing.T) {
+	// Shared lines scattered through unrelated ones make the diff search
+	// most of the edit graph.
+	var old, updated strings.Builder
+	for i := range 40000 {
+		fmt.Fprintf(&old, "old %d\n", i)
+		fmt.Fprintf(&updated, "new %d\n", i)
+		if i%3 == 0 {
+			fmt.Fprintf(&old, "shared %d\n", i)
+			fmt.Fprintf(&updated, "shared %d\n", 40000-i)
+		}
+	}
+	f := newFixtureRepo(t,
+		fixtureCommit{Message: "initial", Files: map[string]string{"big.txt": old.String()}},
+		fixtureCommit{Message: "rewrite", Files: map[string]string{"big.txt": updated.String()}},
+	)
+	from, _ := f.Repo.CommitObject(f.Hashes[0])
+	to, _ := f.Repo.CommitObject(f.Hashes[1])
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	patch, err := treeDiff(ctx, from, to, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("diff took %v after a 20ms deadline", elapsed)
+	}
+	if !patch.truncated {
+		t.Error("patch not marked truncated")
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("ctx.Err() = %v", ctx.Err())
+	}
+}
+
+// legacyFixture has a Latin-1 file and a binary file changed in its second
+// commit.
+func legacyFixture(t *testing.T) *fixtureRepo {
+	return newFixtureRepo(t,
+		fixtureCommit{Message: "Add files\n", Files: map[string]string{
+			"menu.txt": "caf\xe9\n",
+			"logo.bin": "\x00\x01\x02",
+		}},
+		fixtureCommit{Message: "Update files\n", Files: map[string]string{
+			"menu.txt": "caf\xe9 cr\xe8me\n",
+			"logo.bin": "\x00\x01\x03",
+		}},
+	)
+}
+
+func TestRangeDiffHandlerEncoding(t *testing.T) {
+	f := legacyFixture(t)
+
+	tests := []struct {
+		name           string
+		sourceEncoding string
+		wantStatus     int
+		wantEncoding   string
+		wantTranscoded string
+		wantLines      []string
+	}{
+		{name: "base64 without sourceEncoding", wantStatus: http.StatusOK, wantEncoding: "base64",
+			wantLines: []string{"-caf\xe9\n", "+caf\xe9 cr\xe8me\n", "Binary files a/logo.bin and b/logo.bin differ"}},
+		{name: "transcoded from latin1", sourceEncoding: "latin1", wantStatus: http.StatusOK, wantTranscoded: "latin1",
+			wantLines: []string{"-café\n", "+café crème\n", "Binary files a/logo.bin and b/logo.bin differ"}},
+		{name: "unknown sourceEncoding", sourceEncoding: "ebcdic", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"repoId": {f.ID}, "from": {f.Hashes[0].String()}, "to": {f.Hashes[1].String()}, "patch": {"true"}}
+			if tt.sourceEncoding != "" {
+				query.Set("sourceEncoding", tt.sourceEncoding)
+			}
+			w := serve(RangeDiffHandler, http.MethodGet, "/diff/range?"+query.Encode(), nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp RangeDiffResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.PatchEncoding != tt.wantEncoding || resp.TranscodedFrom != tt.wantTranscoded {
+				t.Errorf("patchEncoding, transcodedFrom = %q, %q; want %q, %q",
+					resp.PatchEncoding, resp.TranscodedFrom, tt.wantEncoding, tt.wantTranscoded)
+			}
+			patch := resp.Patch
+			if resp.PatchEncoding == "base64" {
+				raw, err := base64.StdEncoding.DecodeString(patch)
+				if err != nil {
+					t.Fatalf("decode patch: %v", err)
+				}
+				patch = string(raw)
+			}
+			for _, line := range tt.wantLines {
+				if !strings.Contains(patch, line) {
+					t.Errorf("patch missing %q:\n%s", line, patch)
+				}
+			}
+		})
+	}
+}
+
+func TestCommitPatchHandlerEncoding(t *testing.T) {
+	f := legacyFixture(t)
+	target := "/commit/patch?" + url.Values{"repoId": {f.ID}, "hash": {f.Hashes[1].String()}}.Encode()
+
+	t.Run("base64 without sourceEncoding", func(t *testing.T) {
+		w := serve(CommitPatchHandler, http.MethodGet, target, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		headers, body, ok := strings.Cut(w.Body.String(), "\n\n")
+		if !ok {
+			t.Fatalf("no header separator in:\n%s", w.Body)
+		}
+		if !strings.Contains(headers, "Content-Transfer-Encoding: base64") {
+			t.Errorf("headers missing base64 transfer encoding:\n%s", headers)
+		}
+		var raw []byte
+		for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+			if len(line) > 76 {
+				t.Errorf("base64 line of %d columns", len(line))
+			}
+			chunk, err := base64.StdEncoding.DecodeString(line)
+			if err != nil {
+				t.Fatalf("line %q does not decode on its own: %v", line, err)
+			}
+			raw = append(raw, chunk...)
+		}
+		if !strings.Contains(string(raw), "+caf\xe9 cr\xe8me\n") {
+			t.Errorf("decoded body missing the Latin-1 line:\n%s", raw)
+		}
+	})
+
+	t.Run("transcoded from latin1", func(t *testing.T) {
+		w := serve(CommitPatchHandler, http.MethodGet, target+"&sourceEncoding=latin1", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		if got := w.Header().Get(patchTranscodedHeader); got != "latin1" {
+			t.Errorf("%s = %q, want latin1", patchTranscodedHeader, got)
+		}
+		body := w.Body.String()
+		for _, want := range []string{"+café crème\n", "Binary files a/logo.bin and b/logo.bin differ"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("patch missing %q:\n%s", want, body)
+			}
+		}
+		if strings.Contains(body, "Content-Transfer-Encoding") {
+			t.Errorf("transcoded patch should be plain text:\n%s", body)
+		}
+	})
+}
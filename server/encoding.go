@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// windows1252 maps the bytes 0x80-0x9F, where Windows-1252 differs from
+// Latin-1. The five bytes it leaves undefined keep their C1 control code.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+func decodeLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+func decodeWindows1252(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+		if c >= 0x80 && c < 0xa0 {
+			runes[i] = windows1252[c-0x80]
+		}
+	}
+	return string(runes)
+}
+
+// textDecoders are the legacy encodings sourceEncoding accepts. Both are
+// single-byte, so they can be decoded without extra dependencies.
+var textDecoders = map[string]func([]byte) string{
+	"latin1":       decodeLatin1,
+	"iso-8859-1":   decodeLatin1,
+	"windows-1252": decodeWindows1252,
+	"cp1252":       decodeWindows1252,
+}
+
+// parseSourceEncoding reads sourceEncoding, the encoding to assume for text
+// that is not valid UTF-8. It returns nil when none was given.
+func parseSourceEncoding(r *http.Request) (func([]byte) string, error) {
+	name := strings.ToLower(r.URL.Query().Get("sourceEncoding"))
+	if name == "" {
+		return nil, nil
+	}
+	decode, ok := textDecoders[name]
+	if !ok {
+		return nil, fmt.Errorf("sourceEncoding must be latin1, iso-8859-1, windows-1252 or cp1252")
+	}
+	return decode, nil
+}
+
+// jsonSafeText prepares repository text for a JSON string, which can only
+// carry UTF-8; encoding it as is would replace every invalid byte with
+// U+FFFD. Valid UTF-8 is returned unchanged. Otherwise, with decode each
+// invalid line is transcoded from the source encoding and the rest kept, so
+// a diff spanning UTF-8 and legacy files comes out readable, and without it
+// the text is base64-encoded. encoding is "base64" in that case, and
+// transcoded reports whether any line was decoded.
+func jsonSafeText(text string, decode func([]byte) string) (safe, encoding string, transcoded bool) {
+	if utf8.ValidString(text) {
+		return text, "", false
+	}
+	if decode == nil {
+		return base64.StdEncoding.EncodeToString([]byte(text)), "base64", false
+	}
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if !utf8.ValidString(line) {
+			lines[i] = decode([]byte(line))
+		}
+	}
+	return strings.Join(lines, ""), "", true
+}
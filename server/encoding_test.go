@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestJSONSafeText(t *testing.T) {
+	latin1 := "caf\xe9\n"
+	tests := []struct {
+		name           string
+		text           string
+		decode         func([]byte) string
+		want           string
+		wantEncoding   string
+		wantTranscoded bool
+	}{
+		{name: "utf-8 unchanged", text: "café\n", decode: decodeLatin1, want: "café\n"},
+		{name: "latin1 without decoder", text: latin1, want: base64.StdEncoding.EncodeToString([]byte(latin1)), wantEncoding: "base64"},
+		{name: "latin1 decoded", text: latin1, decode: decodeLatin1, want: "café\n", wantTranscoded: true},
+		{name: "only invalid lines decoded", text: "naïve\n" + latin1, decode: decodeLatin1, want: "naïve\ncafé\n", wantTranscoded: true},
+		{name: "windows-1252 quotes", text: "\x93quoted\x94", decode: decodeWindows1252, want: "“quoted”", wantTranscoded: true},
+		{name: "binary without decoder", text: "\x00\xff\xfe", want: "AP/+", wantEncoding: "base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoding, transcoded := jsonSafeText(tt.text, tt.decode)
+			if got != tt.want || encoding != tt.wantEncoding || transcoded != tt.wantTranscoded {
+				t.Errorf("jsonSafeText(%q) = %q, %q, %t; want %q, %q, %t",
+					tt.text, got, encoding, transcoded, tt.want, tt.wantEncoding, tt.wantTranscoded)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Envelope is the response shape with envelope=true: the endpoint's usual
+// body under data, or its error message under error.
+type Envelope struct {
+	Data  json.RawMessage `json:"data"`
+	Meta  EnvelopeMeta    `json:"meta"`
+	Error *EnvelopeError  `json:"error"`
+}
+
+// EnvelopeMeta carries what the plain responses only put in headers or the
+// query string.
+type EnvelopeMeta struct {
+	RequestID   string `json:"requestId,omitempty"`
+	RepoID      string `json:"repoId,omitempty"`
+	Branch      string `json:"branch,omitempty"`
+	GeneratedAt string `json:"generatedAt"`
+	// TotalCount and Links come from the X-Total-Count and Link headers of
+	// paginated lists.
+	TotalCount *int              `json:"totalCount,omitempty"`
+	Links      map[string]string `json:"links,omitempty"`
+	// Truncated is set when the response says it is incomplete, through
+	// X-Patch-Truncated or a truncated field in the body.
+	Truncated       bool     `json:"truncated"`
+	ShallowBoundary []string `json:"shallowBoundary,omitempty"`
+}
+
+type EnvelopeError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// envelopeWriter holds back a JSON body, or the plain text of an error, so
+// it can be wrapped once the handler is done. Any other response, such as an
+// event stream or a patch, is passed through as it is written.
+type envelopeWriter struct {
+	http.ResponseWriter
+	status      int
+	buffered    bool
+	passthrough bool
+	body        bytes.Buffer
+}
+
+func (e *envelopeWriter) WriteHeader(status int) {
+	if e.buffered || e.passthrough {
+		return
+	}
+	e.status = status
+	mediaType, _, _ := mime.ParseMediaType(e.Header().Get("Content-Type"))
+	switch {
+	case mediaType == "application/json" && status < 300:
+		e.buffered = true
+	case mediaType == "text/plain" && status >= 400:
+		e.buffered = true
+	default:
+		e.passthrough = true
+		e.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (e *envelopeWriter) Write(b []byte) (int, error) {
+	if !e.buffered && !e.passthrough {
+		e.WriteHeader(http.StatusOK)
+	}
+	if e.buffered {
+		return e.body.Write(b)
+	}
+	return e.ResponseWriter.Write(b)
+}
+
+func (e *envelopeWriter) Flush() {
+	if !e.passthrough {
+		return
+	}
+	if f, ok := e.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (e *envelopeWriter) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
+
+// finish writes the envelope around a held-back body, keeping the status
+// the handler chose.
+func (e *envelopeWriter) finish(r *http.Request) {
+	if !e.buffered {
+		return
+	}
+	header := e.Header()
+	query := r.URL.Query()
+	envelope := Envelope{
+		Data: json.RawMessage("null"),
+		Meta: EnvelopeMeta{
+			RequestID:   requestIDFrom(r.Context()),
+			RepoID:      query.Get("repoId"),
+			Branch:      query.Get("branch"),
+			GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+			Truncated:   header.Get(patchTruncatedHeader) == "true",
+			Links:       parseLinkHeader(header.Get("Link")),
+		},
+	}
+	if total, err := strconv.Atoi(header.Get(totalCountHeader)); err == nil {
+		envelope.Meta.TotalCount = &total
+	}
+	if boundary := header.Get(shallowBoundaryHeader); boundary != "" {
+		envelope.Meta.ShallowBoundary = strings.Split(boundary, ",")
+	}
+
+	if e.status >= 400 {
+		envelope.Error = &EnvelopeError{Status: e.status, Message: strings.TrimSpace(e.body.String())}
+	} else {
+		envelope.Data = bytes.TrimSpace(e.body.Bytes())
+		var flagged struct {
+			Truncated bool `json:"truncated"`
+		}
+		if json.Unmarshal(envelope.Data, &flagged) == nil && flagged.Truncated {
+			envelope.Meta.Truncated = true
+		}
+	}
+
+	header.Del("Content-Length")
+	header.Del("X-Content-Type-Options")
+	header.Set("Content-Type", "application/json")
+	e.ResponseWriter.WriteHeader(e.status)
+	if err := json.NewEncoder(e.ResponseWriter).Encode(envelope); err != nil {
+		loggerFrom(r.Context()).Error("Error encoding JSON response", "error", err)
+	}
+}
+
+// parseLinkHeader reads a Link header as written by setPaginationHeaders
+// into a map from rel to URL.
+func parseLinkHeader(value string) map[string]string {
+	if value == "" {
+		return nil
+	}
+	links := map[string]string{}
+	for _, link := range strings.Split(value, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+		if !ok {
+			continue
+		}
+		rel := strings.TrimSpace(params)
+		rel = strings.TrimPrefix(rel, "rel=")
+		links[strings.Trim(rel, `"`)] = strings.Trim(target, "<>")
+	}
+	return links
+}
+
+// withEnvelope wraps JSON responses and errors in a common
+// {data, meta, error} shape for requests with envelope=true. Without it
+// responses are left exactly as before.
+func withEnvelope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("envelope") != "true" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		e := &envelopeWriter{ResponseWriter: w}
+		next.ServeHTTP(e, r)
+		e.finish(r)
+	})
+}
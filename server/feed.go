@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+const defaultFeedEntries = 20
+
+// maxFeedEntries caps limit on /feed.
+var maxFeedEntries = envInt("FEED_MAX_ENTRIES", 100)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Content atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email,omitempty"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+func newAtomEntry(c *object.Commit, opts commitOptions) atomEntry {
+	commit := newCommit(c, opts)
+	title, _, _ := strings.Cut(commit.Message, "\n")
+	return atomEntry{
+		ID:      "urn:sha1:" + commit.Hash,
+		Title:   title,
+		Updated: c.Committer.When.UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: commit.Author, Email: commit.Email},
+		Content: atomContent{Type: "text", Text: commit.Message},
+	}
+}
+
+// FeedHandler renders the most recent commits of a branch as an Atom feed,
+// so a repository can be followed in a feed reader. Entries carry the full
+// message and are dated by their committer time, which is when they landed
+// on the branch. limit defaults to 20 and is capped by FEED_MAX_ENTRIES; the
+// usual commit filters and anonymize apply.
+func FeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := defaultFeedEntries
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxFeedEntries)
+	}
+
+	commitOpts, err := parseCommitOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	commitOpts.FullMessage = true
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	branch := query.Get("branch")
+	iter, err := commitLog(r.Context(), repo, branch, filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	feed := atomFeed{
+		ID:    "urn:insightsrepo:" + repoID,
+		Title: repoID + " commits",
+		Link:  atomLink{Rel: "self", Href: r.URL.RequestURI()},
+	}
+	if branch != "" {
+		feed.ID += ":" + branch
+		feed.Title = fmt.Sprintf("%s commits on %s", repoID, branch)
+	}
+	var updated time.Time
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		feed.Entries = append(feed.Entries, newAtomEntry(c, commitOpts))
+		if c.Committer.When.After(updated) {
+			updated = c.Committer.When
+		}
+		if len(feed.Entries) == limit {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+	// A feed must carry an updated time even when it has no entries.
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode feed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	io.WriteString(w, xml.Header)
+	w.Write(out)
+	io.WriteString(w, "\n")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// fieldMask is the set of JSON fields a client asked for with
+// fields=hash,author,date. A nil mask keeps every field.
+type fieldMask map[string]bool
+
+// parseFields reads the fields query parameter for a list of items shaped
+// like item and rejects names the item does not have, so a typo does not
+// silently return empty objects.
+func parseFields(r *http.Request, item any) (fieldMask, error) {
+	value := r.URL.Query().Get("fields")
+	if value == "" {
+		return nil, nil
+	}
+	known := map[string]bool{}
+	for _, name := range jsonFieldNames(reflect.TypeOf(item)) {
+		known[name] = true
+	}
+	mask := fieldMask{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			names := make([]string, 0, len(known))
+			for name := range known {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown field %q; fields must be among %s", name, strings.Join(names, ", "))
+		}
+		mask[name] = true
+	}
+	if len(mask) == 0 {
+		return nil, nil
+	}
+	return mask, nil
+}
+
+// jsonFieldNames lists the names t's exported fields are encoded under,
+// including those of embedded structs.
+func jsonFieldNames(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			names = append(names, jsonFieldNames(field.Type)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// apply returns items, a slice of structs, with only the masked fields of
+// each kept. Fields left out of an item by omitempty stay out.
+func (m fieldMask) apply(items any) (any, error) {
+	if m == nil {
+		return items, nil
+	}
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &objects); err != nil {
+		return nil, err
+	}
+	for _, object := range objects {
+		for name := range object {
+			if !m[name] {
+				delete(object, name)
+			}
+		}
+	}
+	return objects, nil
+}
+
+// writeJSONFields writes items with only the masked fields of each.
+func writeJSONFields(w http.ResponseWriter, r *http.Request, items any, fields fieldMask) {
+	masked, err := fields.apply(items)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to select fields: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, r, masked)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type FileActivityDay struct {
+	Date    string `json:"date"`
+	Commits int    `json:"commits"`
+	LineStats
+}
+
+type FileActivity struct {
+	Path string            `json:"path"`
+	Days []FileActivityDay `json:"days"`
+}
+
+// FileActivityHandler returns the lines added and deleted in a single file
+// per day (UTC, by author date), oldest first. Days on which the file did
+// not change are left out, and a path no commit touched gives an empty
+// series. Changes are counted under the file's name at the time, so history
+// from before a rename is not included.
+func FileActivityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(filter.Paths) != 1 {
+		http.Error(w, "Exactly one path is required", http.StatusBadRequest)
+		return
+	}
+	file := filter.Paths[0]
+
+	repo, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, r.URL.Query().Get("branch"), filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	perDay := map[string]*FileActivityDay{}
+	err = forEachCommitStats(repoID, iter, filter.matches, func(c *object.Commit, modifications []FileModification) error {
+		for _, mod := range modifications {
+			if mod.File != file {
+				continue
+			}
+			date := utcDay(c.Author.When).Format(time.DateOnly)
+			if perDay[date] == nil {
+				perDay[date] = &FileActivityDay{Date: date}
+			}
+			perDay[date].Commits++
+			perDay[date].Additions += mod.Additions
+			perDay[date].Deletions += mod.Deletions
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := FileActivity{Path: file, Days: make([]FileActivityDay, 0, len(perDay))}
+	for _, day := range perDay {
+		resp.Days = append(resp.Days, *day)
+	}
+	sort.Slice(resp.Days, func(i, j int) bool {
+		return resp.Days[i].Date < resp.Days[j].Date
+	})
+
+	writeJSON(w, r, resp)
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type FileModification struct {
+	File      string `json:"file"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	// LFS marks a change to a Git LFS pointer. The pointer's own lines are
+	// not counted, since they say nothing about the file they stand for.
+	LFS bool `json:"lfs,omitempty"`
+}
+
+// commitModifications returns the per-file changes of c against its first
+// parent, from the stats cache when they were computed before. The result
+// must not be modified.
+func commitModifications(c *object.Commit) ([]FileModification, error) {
+	if modifications, ok := commitStats.get(c.Hash); ok {
+		return modifications, nil
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		// The boundary commit of a shallow clone has no parent to diff
+		// against, so its changes are unknown rather than an error. They
+		// are not cached since deepening the clone makes them known.
+		if _, parentErr := c.Parent(0); errors.Is(parentErr, plumbing.ErrObjectNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	modifications := make([]FileModification, 0, len(stats))
+	trees := lfsTrees{commit: c}
+	for _, stat := range stats {
+		mod := FileModification{
+			File:      stat.Name,
+			Additions: stat.Addition,
+			Deletions: stat.Deletion,
+		}
+		// Only a side with at most a pointer's worth of lines can be one,
+		// so most changes never load a blob.
+		if mod.Additions <= lfsPointerLines && isLFSPointerIn(trees.to(), mod.File) {
+			mod.Additions = 0
+			mod.LFS = true
+		}
+		if mod.Deletions <= lfsPointerLines && isLFSPointerIn(trees.from(), mod.File) {
+			mod.Deletions = 0
+			mod.LFS = true
+		}
+		modifications = append(modifications, mod)
+	}
+	commitStats.put(c.Hash, modifications)
+	return modifications, nil
+}
+
+// lfsTrees loads the trees of a commit and of its first parent, which
+// Commit.Stats diffs against, on first use.
+type lfsTrees struct {
+	commit               *object.Commit
+	toTree, fromTree     *object.Tree
+	toLoaded, fromLoaded bool
+}
+
+func (t *lfsTrees) to() *object.Tree {
+	if !t.toLoaded {
+		t.toLoaded = true
+		t.toTree, _ = t.commit.Tree()
+	}
+	return t.toTree
+}
+
+func (t *lfsTrees) from() *object.Tree {
+	if !t.fromLoaded {
+		t.fromLoaded = true
+		if parent, err := t.commit.Parent(0); err == nil {
+			t.fromTree, _ = parent.Tree()
+		}
+	}
+	return t.fromTree
+}
+
+func StreamFilesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	release, ok := acquireStream(w, repoID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	setSSEHeaders(w)
+
+	sendSSEMessage(w, r, "status", map[string]interface{}{
+		"message":   "Streaming file modifications",
+		"repoId":    repoID,
+		"requestId": requestIDFrom(r.Context()),
+	})
+
+	iter, err := commitLog(r.Context(), repo, "", filter.logOptions())
+	if err != nil {
+		sendSSEMessage(w, r, "error", map[string]string{
+			"message": fmt.Sprintf("Failed to get commit logs: %v", err),
+		})
+		return
+	}
+
+	ctx := r.Context()
+	commits := 0
+	stream := newSSEBatcher(w)
+	err = iter.ForEach(func(c *object.Commit) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !filter.matches(c) {
+			return nil
+		}
+
+		modifications, err := commitModifications(c)
+		if err != nil {
+			sendSSEMessage(stream, r, "error", map[string]string{
+				"message": fmt.Sprintf("Failed to get stats for %s: %v", c.Hash, err),
+				"hash":    c.Hash.String(),
+			})
+			return nil
+		}
+
+		sendSSEMessage(stream, r, "files", map[string]interface{}{
+			"hash":          c.Hash.String(),
+			"date":          c.Author.When.Format(time.RFC3339),
+			"modifications": modifications,
+		})
+		commits++
+		return nil
+	})
+	stream.Close()
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		sendSSEMessage(w, r, "error", map[string]string{
+			"message": fmt.Sprintf("Error processing commits: %v", err),
+		})
+	}
+
+	sendSSEMessage(w, r, "complete", map[string]interface{}{
+		"message": "File modifications stream complete",
+		"repoId":  repoID,
+		"commits": commits,
+	})
+}
+
+type CommitFileModification struct {
+	Hash      string `json:"hash"`
+	Date      string `json:"date"`
+	File      string `json:"file"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	LFS       bool   `json:"lfs,omitempty"`
+}
+
+type FileChurn struct {
+	File         string `json:"file"`
+	Commits      int    `json:"commits"`
+	Additions    int    `json:"additions"`
+	Deletions    int    `json:"deletions"`
+	Churn        int    `json:"churn"`
+	LastModified string `json:"lastModified"`
+
+	lastModified time.Time
+}
+
+var fileChurnSorts = map[string]func(a, b *FileChurn) bool{
+	"churn":     func(a, b *FileChurn) bool { return a.Churn > b.Churn },
+	"additions": func(a, b *FileChurn) bool { return a.Additions > b.Additions },
+	"deletions": func(a, b *FileChurn) bool { return a.Deletions > b.Deletions },
+	"recent":    func(a, b *FileChurn) bool { return a.lastModified.After(b.lastModified) },
+}
+
+// churnCounter aggregates modifications per file path.
+type churnCounter map[string]*FileChurn
+
+func (c churnCounter) add(mod FileModification, when time.Time) {
+	file := c[mod.File]
+	if file == nil {
+		file = &FileChurn{File: mod.File}
+		c[mod.File] = file
+	}
+	file.Commits++
+	file.Additions += mod.Additions
+	file.Deletions += mod.Deletions
+	file.Churn += mod.Additions + mod.Deletions
+	if when.After(file.lastModified) {
+		file.lastModified = when
+		file.LastModified = when.Format(time.RFC3339)
+	}
+}
+
+// sorted orders the files with less, breaking ties by path, and keeps at
+// most top entries when top is positive.
+func (c churnCounter) sorted(less func(a, b *FileChurn) bool, top int) []*FileChurn {
+	churn := make([]*FileChurn, 0, len(c))
+	for _, file := range c {
+		churn = append(churn, file)
+	}
+	sort.Slice(churn, func(i, j int) bool {
+		if less(churn[i], churn[j]) != less(churn[j], churn[i]) {
+			return less(churn[i], churn[j])
+		}
+		return churn[i].File < churn[j].File
+	})
+	if top > 0 && len(churn) > top {
+		churn = churn[:top]
+	}
+	return churn
+}
+
+// FileModificationsHandler lists per-commit file changes in walk order, or,
+// with sort=churn|additions|deletions|recent, one aggregated entry per file.
+// offset and limit select a page of the list after top is applied.
+func FileModificationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	sortBy := query.Get("sort")
+	less, aggregate := fileChurnSorts[sortBy]
+	if sortBy != "" && !aggregate {
+		http.Error(w, "sort must be one of churn, additions, deletions or recent", http.StatusBadRequest)
+		return
+	}
+
+	top := 0
+	if value := query.Get("top"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "top must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	offset, ok := parseOffset(r)
+	if !ok {
+		http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+
+	var item any = CommitFileModification{}
+	if aggregate {
+		item = FileChurn{}
+	}
+	fields, err := parseFields(r, item)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, "", filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	entries := []CommitFileModification{}
+	files := churnCounter{}
+	err = forEachCommitStats(repoID, iter, filter.matches, func(c *object.Commit, modifications []FileModification) error {
+		for _, mod := range modifications {
+			if !filter.includesFile(mod.File) {
+				continue
+			}
+			if !aggregate {
+				entries = append(entries, CommitFileModification{
+					Hash:      c.Hash.String(),
+					Date:      c.Author.When.Format(time.RFC3339),
+					File:      mod.File,
+					Additions: mod.Additions,
+					Deletions: mod.Deletions,
+					LFS:       mod.LFS,
+				})
+				continue
+			}
+
+			files.add(mod, c.Author.When)
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if !aggregate {
+		if top > 0 && len(entries) > top {
+			entries = entries[:top]
+		}
+		start, end, links := offsetPage(r, len(entries), offset, limit)
+		setPaginationHeaders(w, len(entries), links)
+		writeJSONFields(w, r, entries[start:end], fields)
+		return
+	}
+
+	sorted := files.sorted(less, top)
+	start, end, links := offsetPage(r, len(sorted), offset, limit)
+	setPaginationHeaders(w, len(sorted), links)
+	writeJSONFields(w, r, sorted[start:end], fields)
+}
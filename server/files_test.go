@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestFileModificationsHandler(t *testing.T) {
+	f := newFixtureRepo(t,
+		fixtureCommit{Message: "Initial commit\n", Files: map[string]string{"main.go": "package main\n", "README.md": "hi\n"}},
+		fixtureCommit{Message: "Grow main\n", Files: map[string]string{"main.go": "package main\n\nfunc main() {}\n"}},
+		fixtureCommit{Message: "Drop readme\n", Remove: []string{"README.md"}},
+	)
+
+	t.Run("per commit", func(t *testing.T) {
+		w := serve(FileModificationsHandler, http.MethodGet, "/files?repoId="+f.ID, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		var got []CommitFileModification
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		want := []CommitFileModification{
+			{Hash: f.Hashes[2].String(), File: "README.md", Deletions: 1},
+			{Hash: f.Hashes[1].String(), File: "main.go", Additions: 2},
+			{Hash: f.Hashes[0].String(), File: "README.md", Additions: 1},
+			{Hash: f.Hashes[0].String(), File: "main.go", Additions: 1},
+		}
+		if len(got) != len(want) {
+			t.Fatalf("got %d modifications, want %d: %+v", len(got), len(want), got)
+		}
+		for i := range want {
+			got[i].Date = ""
+			if got[i] != want[i] {
+				t.Errorf("modification %d = %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	})
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		wantStatus int
+		wantFiles  []string
+		wantChurn  []int
+	}{
+		{name: "by churn", query: url.Values{"sort": {"churn"}}, wantStatus: http.StatusOK, wantFiles: []string{"main.go", "README.md"}, wantChurn: []int{3, 2}},
+		{name: "top", query: url.Values{"sort": {"churn"}, "top": {"1"}}, wantStatus: http.StatusOK, wantFiles: []string{"main.go"}, wantChurn: []int{3}},
+		{name: "path filter", query: url.Values{"sort": {"churn"}, "path": {"README.md"}}, wantStatus: http.StatusOK, wantFiles: []string{"README.md"}, wantChurn: []int{2}},
+		{name: "ignore glob", query: url.Values{"sort": {"churn"}, "ignore": {"*.md"}}, wantStatus: http.StatusOK, wantFiles: []string{"main.go"}, wantChurn: []int{3}},
+		{name: "unknown sort", query: url.Values{"sort": {"size"}}, wantStatus: http.StatusBadRequest},
+		{name: "invalid top", query: url.Values{"sort": {"churn"}, "top": {"-1"}}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Set("repoId", f.ID)
+			w := serve(FileModificationsHandler, http.MethodGet, "/files?"+tt.query.Encode(), nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got []FileChurn
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(got) != len(tt.wantFiles) {
+				t.Fatalf("got %d files, want %d: %+v", len(got), len(tt.wantFiles), got)
+			}
+			for i := range got {
+				if got[i].File != tt.wantFiles[i] || got[i].Churn != tt.wantChurn[i] {
+					t.Errorf("file %d = %s churn %d, want %s churn %d", i, got[i].File, got[i].Churn, tt.wantFiles[i], tt.wantChurn[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitFilter holds the query parameters that drop commits from a walk.
+// Every endpoint that walks history applies the same filter so the numbers
+// they report stay consistent with each other.
+type commitFilter struct {
+	ExcludeBots  bool
+	ExcludeEmpty bool
+	// Paths restricts the walk to commits touching any of these path
+	// prefixes.
+	Paths []string
+	// Authors keeps commits whose mailmap-resolved author name or email, or
+	// canonical id under IDENTITY_RULES, equals any of them, ignoring case.
+	Authors []string
+	// Since drops commits authored before it when non-zero.
+	Since time.Time
+	// Ignore lists globs dropped from per-file results. It is set by
+	// repeated ignore= parameters, or by the repository config when none
+	// are given.
+	Ignore []string
+
+	ignoreSet bool
+	mailmap   *mailmap
+	bots      []*regexp.Regexp
+}
+
+func parseCommitFilter(r *http.Request) (commitFilter, error) {
+	query := r.URL.Query()
+	filter := commitFilter{
+		ExcludeBots:  query.Get("excludeBots") == "true",
+		ExcludeEmpty: query.Get("excludeEmpty") == "true",
+	}
+
+	for _, p := range query["path"] {
+		p = strings.Trim(p, "/")
+		if p == "" {
+			return filter, fmt.Errorf("path must not be empty")
+		}
+		filter.Paths = append(filter.Paths, p)
+	}
+
+	for _, author := range query["author"] {
+		if author = strings.TrimSpace(author); author != "" {
+			filter.Authors = append(filter.Authors, author)
+		}
+	}
+
+	if values, ok := query["ignore"]; ok {
+		filter.ignoreSet = true
+		for _, pattern := range values {
+			if pattern = strings.TrimSpace(pattern); pattern == "" {
+				continue
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return filter, fmt.Errorf("invalid ignore pattern %q", pattern)
+			}
+			filter.Ignore = append(filter.Ignore, pattern)
+		}
+	}
+
+	if value := query.Get("since"); value != "" {
+		since, err := parseSince(value)
+		if err != nil {
+			return filter, err
+		}
+		filter.Since = since
+	}
+	return filter, nil
+}
+
+// parseSince accepts an RFC 3339 timestamp or a plain YYYY-MM-DD date, which
+// is taken as midnight UTC.
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("since must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+}
+
+// bind attaches the repository state the filter needs: the mailmap used to
+// match Authors and the defaults from the repository config. It must be
+// called before matches on every walk.
+func (f *commitFilter) bind(ctx context.Context, repo *git.Repository) {
+	if len(f.Authors) > 0 {
+		f.mailmap = loadMailmap(repo)
+	}
+	config := repoConfigFor(ctx, repo)
+	if !f.ignoreSet {
+		f.Ignore = config.IgnorePaths
+	}
+	f.bots = compiledBotPatterns(config)
+}
+
+func (f commitFilter) matchesPath(file string) bool {
+	for _, prefix := range f.Paths {
+		if underPath(file, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// underPath reports whether file is prefix itself or inside it.
+func underPath(file, prefix string) bool {
+	return file == prefix || strings.HasPrefix(file, prefix+"/")
+}
+
+// includesFile reports whether per-file results should include file. With
+// no paths or ignore patterns set every file is included.
+func (f commitFilter) includesFile(file string) bool {
+	if isIgnoredByPatterns(f.Ignore, file) {
+		return false
+	}
+	return len(f.Paths) == 0 || f.matchesPath(file)
+}
+
+// logOptions returns the base log options for a filtered walk. Paths are
+// not passed to go-git's PathFilter, which diffs each commit against the
+// previous one in walk order rather than against its parent and so reports
+// unrelated commits on non-linear history; matches handles them instead.
+func (f commitFilter) logOptions() *git.LogOptions {
+	return &git.LogOptions{}
+}
+
+func (f commitFilter) matches(c *object.Commit) bool {
+	if f.ExcludeBots && f.isBot(c.Author) {
+		return false
+	}
+	if f.ExcludeEmpty && isEmptyCommit(c) {
+		return false
+	}
+	if !f.Since.IsZero() && c.Author.When.Before(f.Since) {
+		return false
+	}
+	if len(f.Authors) > 0 && !f.matchesAnyAuthor(c.Author) {
+		return false
+	}
+	if len(f.Paths) > 0 && !f.touchesPaths(c) {
+		return false
+	}
+	return true
+}
+
+func (f commitFilter) isBot(author object.Signature) bool {
+	if f.bots == nil {
+		return isBot(author)
+	}
+	return matchesBotPatterns(f.bots, author)
+}
+
+func (f commitFilter) matchesAnyAuthor(sig object.Signature) bool {
+	author := identity{Name: sig.Name, Email: sig.Email}
+	if f.mailmap != nil {
+		author = f.mailmap.resolve(sig.Name, sig.Email)
+	}
+	key := canonicalID(author)
+	for _, want := range f.Authors {
+		if matchesAuthor(author, want) || strings.EqualFold(key, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// touchesPaths reports whether c changes any filtered path relative to its
+// first parent.
+func (f commitFilter) touchesPaths(c *object.Commit) bool {
+	return len(touchedPaths(c, f.Paths)) > 0
+}
+
+// touchedPaths returns the path prefixes, in the order given, under which c
+// changes something relative to its first parent. Root commits are compared
+// against an empty tree. A commit that cannot be diffed touches nothing.
+func touchedPaths(c *object.Commit, prefixes []string) []string {
+	changes, err := commitChanges(context.Background(), c)
+	if err != nil {
+		return nil
+	}
+
+	var touched []string
+	for _, prefix := range prefixes {
+		for _, change := range changes {
+			if underPath(change.From.Name, prefix) || underPath(change.To.Name, prefix) {
+				touched = append(touched, prefix)
+				break
+			}
+		}
+	}
+	return touched
+}
+
+// commitChanges diffs c's tree against its first parent's, or an empty tree
+// for a root commit. Only tree entries are compared. A commit whose parent
+// is missing from a shallow clone has no changes.
+func commitChanges(ctx context.Context, c *object.Commit) (object.Changes, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	var parentTree *object.Tree
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, err
+		}
+	}
+	return object.DiffTreeContext(ctx, parentTree, tree)
+}
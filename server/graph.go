@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+const defaultGraphLimit = 200
+
+type GraphNode struct {
+	Hash    string   `json:"hash"`
+	Message string   `json:"message"`
+	Author  string   `json:"author"`
+	Date    string   `json:"date"`
+	Parents []string `json:"parents"`
+	Lane    int      `json:"lane"`
+	Refs    []string `json:"refs,omitempty"`
+}
+
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type GraphResponse struct {
+	Nodes     []GraphNode `json:"nodes"`
+	Edges     []GraphEdge `json:"edges"`
+	Truncated bool        `json:"truncated"`
+}
+
+// refDecorations maps commit hashes to the branch, tag and HEAD names that
+// point at them. Annotated tags are peeled to their target commit.
+func refDecorations(repo *git.Repository) (map[plumbing.Hash][]string, error) {
+	decorations := map[plumbing.Hash][]string{}
+
+	if head, err := repo.Head(); err == nil {
+		decorations[head.Hash()] = append(decorations[head.Hash()], "HEAD")
+	}
+
+	refs, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+
+		name := ref.Name()
+		hash := ref.Hash()
+		switch {
+		case name.IsBranch(), name.IsRemote():
+		case name.IsTag():
+			if tag, err := repo.TagObject(hash); err == nil {
+				commit, err := tag.Commit()
+				if err != nil {
+					return nil
+				}
+				hash = commit.Hash
+			}
+		default:
+			return nil
+		}
+		decorations[hash] = append(decorations[hash], name.Short())
+		return nil
+	})
+	return decorations, err
+}
+
+func GraphHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := defaultGraphLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	anonymize := parseAnonymize(r)
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	decorations, err := refDecorations(repo)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read references: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	iter, err := repoLog(repo, &git.LogOptions{All: true, Order: git.LogOrderCommitterTime})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := GraphResponse{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	included := map[plumbing.Hash]bool{}
+	// lanes holds, per lane, the hash of the commit expected to appear next.
+	var lanes []plumbing.Hash
+
+	err = iter.ForEach(func(c *object.Commit) error {
+		if len(resp.Nodes) >= limit {
+			resp.Truncated = true
+			return storer.ErrStop
+		}
+
+		lane := -1
+		for i, expected := range lanes {
+			if expected == c.Hash {
+				if lane == -1 {
+					lane = i
+				} else {
+					lanes[i] = plumbing.ZeroHash
+				}
+			}
+		}
+		if lane == -1 {
+			for i, expected := range lanes {
+				if expected.IsZero() {
+					lane = i
+					break
+				}
+			}
+			if lane == -1 {
+				lanes = append(lanes, plumbing.ZeroHash)
+				lane = len(lanes) - 1
+			}
+		}
+
+		parents := make([]string, len(c.ParentHashes))
+		for i, parent := range c.ParentHashes {
+			parents[i] = parent.String()
+		}
+
+		if len(c.ParentHashes) == 0 {
+			lanes[lane] = plumbing.ZeroHash
+		} else {
+			lanes[lane] = c.ParentHashes[0]
+			for _, parent := range c.ParentHashes[1:] {
+				placed := false
+				for i, expected := range lanes {
+					if expected == parent {
+						placed = true
+						break
+					} else if expected.IsZero() && i != lane {
+						lanes[i] = parent
+						placed = true
+						break
+					}
+				}
+				if !placed {
+					lanes = append(lanes, parent)
+				}
+			}
+		}
+
+		resp.Nodes = append(resp.Nodes, GraphNode{
+			Hash:    c.Hash.String(),
+			Message: redactText(commitSubject(c.Message)),
+			Author:  authorName(c.Author, anonymize),
+			Date:    c.Author.When.Format(time.RFC3339),
+			Parents: parents,
+			Lane:    lane,
+			Refs:    decorations[c.Hash],
+		})
+		included[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Edges to parents outside the window are dropped so every edge has
+	// both endpoints in the node list.
+	for _, node := range resp.Nodes {
+		for _, parent := range node.Parents {
+			if included[plumbing.NewHash(parent)] {
+				resp.Edges = append(resp.Edges, GraphEdge{From: node.Hash, To: parent})
+			}
+		}
+	}
+
+	writeJSON(w, r, resp)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"sync/atomic"
+)
+
+var (
+	activeClones        atomic.Int64
+	maxConcurrentClones = int64(envInt("MAX_CONCURRENT_CLONES", 4))
+)
+
+type ReadinessStatus struct {
+	Status              string           `json:"status"`
+	ActiveClones        int64            `json:"activeClones"`
+	MaxConcurrentClones int64            `json:"maxConcurrentClones"`
+	Saturated           bool             `json:"saturated"`
+	ReposDirAvailable   bool             `json:"reposDirAvailable"`
+	ActiveStreams       int64            `json:"activeStreams"`
+	StatsCache          StatsCacheStatus `json:"statsCache"`
+}
+
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, map[string]string{"status": "ok"})
+}
+
+// ReadyHandler reports 503 while the instance is already running as many
+// clones as it allows, so load balancers route new clone requests elsewhere.
+func ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	status := ReadinessStatus{
+		Status:              "ready",
+		ActiveClones:        activeClones.Load(),
+		MaxConcurrentClones: maxConcurrentClones,
+		ActiveStreams:       activeStreams.Load(),
+		StatsCache:          commitStats.status(),
+	}
+	status.Saturated = maxConcurrentClones > 0 && status.ActiveClones >= maxConcurrentClones
+
+	if info, err := os.Stat(reposDir); err == nil && info.IsDir() {
+		status.ReposDirAvailable = true
+	}
+
+	if status.Saturated || !status.ReposDirAvailable {
+		status.Status = "unavailable"
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	writeJSON(w, r, status)
+}
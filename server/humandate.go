@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var humanDateUnits = []struct {
+	name string
+	size time.Duration
+}{
+	{"year", 365 * 24 * time.Hour},
+	{"month", 30 * 24 * time.Hour},
+	{"week", 7 * 24 * time.Hour},
+	{"day", 24 * time.Hour},
+	{"hour", time.Hour},
+	{"minute", time.Minute},
+}
+
+// humanDate renders t relative to now, e.g. "3 days ago" or "in 2 hours".
+// The fuzzy form uses the largest whole unit; the exact form adds the next
+// unit down as well ("3 days 4 hours ago"). Months and years are 30 and 365
+// days.
+func humanDate(t, now time.Time, exact bool) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d < time.Minute {
+		return "just now"
+	}
+
+	var parts []string
+	for _, unit := range humanDateUnits {
+		if d < unit.size {
+			if len(parts) > 0 {
+				break
+			}
+			continue
+		}
+		n := int(d / unit.size)
+		d -= time.Duration(n) * unit.size
+		if n == 1 {
+			parts = append(parts, "1 "+unit.name)
+		} else {
+			parts = append(parts, fmt.Sprintf("%d %ss", n, unit.name))
+		}
+		if !exact || len(parts) == 2 {
+			break
+		}
+	}
+
+	if future {
+		return "in " + strings.Join(parts, " ")
+	}
+	return strings.Join(parts, " ") + " ago"
+}
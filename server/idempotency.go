@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+var (
+	errIdempotencyKeyReused = errors.New("Idempotency-Key was already used for a different repository")
+	errCloneAbandoned       = errors.New("the request holding this Idempotency-Key was cancelled")
+)
+
+// idempotentClone is the shared outcome of the first request that used an
+// Idempotency-Key. done is closed once err is set.
+type idempotentClone struct {
+	fingerprint string
+	done        chan struct{}
+	err         error
+	expires     time.Time
+}
+
+// wait blocks until the original request has finished and returns its error,
+// or the context error if ctx ends first.
+func (c *idempotentClone) wait(ctx context.Context) error {
+	select {
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// idempotencyStore remembers clone requests by Idempotency-Key. In-progress
+// entries never expire; finished ones are kept for ttl.
+type idempotencyStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*idempotentClone
+}
+
+var cloneRequests = &idempotencyStore{
+	ttl:     time.Duration(envInt("IDEMPOTENCY_KEY_TTL_SECONDS", 600)) * time.Second,
+	entries: map[string]*idempotentClone{},
+}
+
+// begin registers key for the request described by fingerprint. The caller
+// owns the entry, and must finish it, when owner is true; otherwise it should
+// wait for the existing entry. An empty key always yields a nil, owned entry.
+func (s *idempotencyStore) begin(key, fingerprint string) (*idempotentClone, bool, error) {
+	if key == "" {
+		return nil, true, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range s.entries {
+		if !entry.expires.IsZero() && now.After(entry.expires) {
+			delete(s.entries, k)
+		}
+	}
+
+	if entry, ok := s.entries[key]; ok {
+		if entry.fingerprint != fingerprint {
+			return nil, false, errIdempotencyKeyReused
+		}
+		return entry, false, nil
+	}
+
+	entry := &idempotentClone{fingerprint: fingerprint, done: make(chan struct{})}
+	s.entries[key] = entry
+	return entry, true, nil
+}
+
+// finish records the outcome of an owned entry and releases its waiters. A
+// request cancelled by its own client is not remembered, so the key can be
+// retried; waiters see errCloneAbandoned and may take it over.
+func (s *idempotencyStore) finish(entry *idempotentClone, err error) {
+	if entry == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if errors.Is(err, context.Canceled) {
+		for k, e := range s.entries {
+			if e == entry {
+				delete(s.entries, k)
+			}
+		}
+		err = errCloneAbandoned
+	}
+	entry.err = err
+	entry.expires = time.Now().Add(s.ttl)
+	close(entry.done)
+}
+
+// abandon gives up an owned entry before any work was done, like a
+// cancelled request: the key is forgotten and waiters may take it over.
+func (s *idempotencyStore) abandon(entry *idempotentClone) {
+	s.finish(entry, context.Canceled)
+}
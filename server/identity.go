@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// identityRules are the canonicalisation rules from IDENTITY_RULES, one
+// `pattern => replacement` per line. They merge identities a mailmap cannot
+// describe, such as every GitHub noreply address of one account:
+//
+//	^(\d+)\+.*@users\.noreply\.github\.com$ => github:$1
+//
+// No rules are applied when it is unset. main compiles them at startup.
+var identityRules []identityRule
+
+type identityRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// compileIdentityRules rejects a malformed rule so a misconfigured
+// deployment fails at startup instead of silently splitting people.
+func compileIdentityRules(value string) ([]identityRule, error) {
+	var rules []identityRule
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		pattern, replacement, ok := strings.Cut(line, "=>")
+		if !ok {
+			return nil, fmt.Errorf("invalid IDENTITY_RULES entry %q: missing =>", line)
+		}
+		re, err := regexp.Compile(strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid IDENTITY_RULES entry %q: %w", line, err)
+		}
+		rules = append(rules, identityRule{pattern: re, replacement: strings.TrimSpace(replacement)})
+	}
+	return rules, nil
+}
+
+// canonicalID is the key an author is counted under once the mailmap has
+// been applied: the lowercased email, or the name when there is no email,
+// rewritten by the first identity rule that matches it.
+func canonicalID(author identity) string {
+	key := strings.ToLower(author.Email)
+	if key == "" {
+		key = strings.ToLower(author.Name)
+	}
+	for _, rule := range identityRules {
+		if rule.pattern.MatchString(key) {
+			return rule.pattern.ReplaceAllString(key, rule.replacement)
+		}
+	}
+	return key
+}
+
+// listedCanonicalID is canonicalID as shown in a response. It is left out
+// under anonymize, since it is usually the author's email.
+func listedCanonicalID(key string, anonymize bool) string {
+	if anonymize {
+		return ""
+	}
+	return redactEmail(key)
+}
+
+// authorLabels names authors in results keyed by display name. Each
+// canonical identity keeps the first name it was seen under, so aliases the
+// mailmap or the identity rules merge share one entry.
+type authorLabels map[string]string
+
+func (l authorLabels) label(author identity, anonymize bool) string {
+	key := canonicalID(author)
+	if label, ok := l[key]; ok {
+		return label
+	}
+	label := author.Name
+	if anonymize {
+		label = pseudonymName(author.Name, author.Email)
+	}
+	l[key] = label
+	return label
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileIdentityRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+		email   string
+		wantID  string
+	}{
+		{name: "unset", value: "", email: "Alice@Example.com", wantID: "alice@example.com"},
+		{name: "noreply rule", value: `^(\d+)\+.*@users\.noreply\.github\.com$ => github:$1`,
+			email: "1234+alice@users.noreply.github.com", wantID: "github:1234"},
+		{name: "blank lines skipped", value: "\n  \n^bob@old\\.example$ => bob@example.com\n", email: "bob@old.example", wantID: "bob@example.com"},
+		{name: "missing arrow", value: "^alice$", wantErr: `invalid IDENTITY_RULES entry "^alice$": missing =>`},
+		{name: "bad pattern", value: "([ => x", wantErr: `invalid IDENTITY_RULES entry "([ => x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := compileIdentityRules(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			previous := identityRules
+			identityRules = rules
+			defer func() { identityRules = previous }()
+			if got := canonicalID(identity{Email: tt.email}); got != tt.wantID {
+				t.Errorf("canonicalID(%q) = %q, want %q", tt.email, got, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type CommitInterval struct {
+	ContributorCount
+	// MedianHours and MeanHours are the gaps between the author's
+	// consecutive commits by author date. They are null for an author with
+	// a single commit.
+	MedianHours *float64 `json:"medianHours"`
+	MeanHours   *float64 `json:"meanHours"`
+}
+
+// authorCommitTimes counts the commits iter yields that filter matches per
+// author, and returns each author's author dates, oldest first, under the
+// key authors counts them by.
+func authorCommitTimes(iter object.CommitIter, filter commitFilter, authors *authorCounter) (map[string][]time.Time, error) {
+	times := map[string][]time.Time{}
+	err := iter.ForEach(func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		authors.add(c.Author)
+		_, key := authors.resolve(c.Author)
+		times[key] = append(times[key], c.Author.When)
+		return nil
+	})
+	for _, t := range times {
+		sort.Slice(t, func(i, j int) bool { return t[i].Before(t[j]) })
+	}
+	return times, err
+}
+
+// commitGaps returns the median and mean gap in hours between consecutive
+// sorted times. ok is false with fewer than two times.
+func commitGaps(times []time.Time) (median, mean float64, ok bool) {
+	if len(times) < 2 {
+		return 0, 0, false
+	}
+	gaps := make([]float64, len(times)-1)
+	var total float64
+	for i := range gaps {
+		gaps[i] = times[i+1].Sub(times[i]).Hours()
+		total += gaps[i]
+	}
+	sort.Float64s(gaps)
+	median = gaps[len(gaps)/2]
+	if len(gaps)%2 == 0 {
+		median = (gaps[len(gaps)/2-1] + gaps[len(gaps)/2]) / 2
+	}
+	return median, total / float64(len(gaps)), true
+}
+
+// CommitIntervalsHandler reports each author's rhythm: the median and mean
+// time between their consecutive commits. Authors are mailmap-resolved and
+// bots are always left out. Authors are listed by commit count, most active
+// first.
+func CommitIntervalsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.ExcludeBots = true
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, r.URL.Query().Get("branch"), filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	authors := newAuthorCounter(loadMailmap(repo))
+	authors.anonymize = parseAnonymize(r)
+	times, err := authorCommitTimes(iter, filter, authors)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	intervals := make([]CommitInterval, 0, authors.len())
+	for key, count := range authors.counts {
+		interval := CommitInterval{ContributorCount: authors.listed(count)}
+		if median, mean, ok := commitGaps(times[key]); ok {
+			interval.MedianHours, interval.MeanHours = &median, &mean
+		}
+		intervals = append(intervals, interval)
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		if intervals[i].Commits != intervals[j].Commits {
+			return intervals[i].Commits > intervals[j].Commits
+		}
+		return intervals[i].Name < intervals[j].Name
+	})
+
+	writeJSON(w, r, intervals)
+}
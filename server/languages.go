@@ -0,0 +1,205 @@
+package main
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var extensionLanguages = map[string]string{
+	".go":     "Go",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".js":     "JavaScript",
+	".jsx":    "JavaScript",
+	".mjs":    "JavaScript",
+	".cjs":    "JavaScript",
+	".py":     "Python",
+	".rb":     "Ruby",
+	".java":   "Java",
+	".kt":     "Kotlin",
+	".kts":    "Kotlin",
+	".scala":  "Scala",
+	".swift":  "Swift",
+	".m":      "Objective-C",
+	".c":      "C",
+	".h":      "C",
+	".cc":     "C++",
+	".cpp":    "C++",
+	".cxx":    "C++",
+	".hpp":    "C++",
+	".cs":     "C#",
+	".fs":     "F#",
+	".rs":     "Rust",
+	".php":    "PHP",
+	".dart":   "Dart",
+	".lua":    "Lua",
+	".pl":     "Perl",
+	".r":      "R",
+	".ex":     "Elixir",
+	".exs":    "Elixir",
+	".erl":    "Erlang",
+	".hs":     "Haskell",
+	".clj":    "Clojure",
+	".vue":    "Vue",
+	".svelte": "Svelte",
+	".sh":     "Shell",
+	".bash":   "Shell",
+	".zsh":    "Shell",
+	".ps1":    "PowerShell",
+	".sql":    "SQL",
+	".html":   "HTML",
+	".htm":    "HTML",
+	".css":    "CSS",
+	".scss":   "SCSS",
+	".sass":   "Sass",
+	".less":   "Less",
+	".json":   "JSON",
+	".yaml":   "YAML",
+	".yml":    "YAML",
+	".toml":   "TOML",
+	".xml":    "XML",
+	".md":     "Markdown",
+	".mdx":    "Markdown",
+	".rst":    "reStructuredText",
+	".csv":    "CSV",
+	".txt":    "Text",
+	".proto":  "Protocol Buffers",
+	".tf":     "HCL",
+}
+
+var filenameLanguages = map[string]string{
+	"Dockerfile":  "Dockerfile",
+	"Makefile":    "Makefile",
+	"CMakeLists":  "CMake",
+	"Jenkinsfile": "Groovy",
+}
+
+// dataLanguages are counted in the breakdown but never chosen as the
+// primary language, since they rarely describe what a project is written in.
+var dataLanguages = map[string]bool{
+	"JSON":             true,
+	"YAML":             true,
+	"TOML":             true,
+	"XML":              true,
+	"Markdown":         true,
+	"reStructuredText": true,
+	"CSV":              true,
+	"Text":             true,
+	"HTML":             true,
+	"CSS":              true,
+}
+
+var ignoredPathSegments = map[string]bool{
+	"vendor":       true,
+	"node_modules": true,
+	"third_party":  true,
+	"dist":         true,
+	"build":        true,
+	".git":         true,
+}
+
+func languageForPath(filePath string) string {
+	base := path.Base(filePath)
+	if lang, ok := filenameLanguages[strings.TrimSuffix(base, path.Ext(base))]; ok {
+		return lang
+	}
+	return extensionLanguages[strings.ToLower(path.Ext(base))]
+}
+
+func isIgnoredPath(filePath string) bool {
+	for _, segment := range strings.Split(filePath, "/") {
+		if ignoredPathSegments[segment] {
+			return true
+		}
+	}
+	return false
+}
+
+type LanguageStat struct {
+	Language   string  `json:"language"`
+	Bytes      int64   `json:"bytes"`
+	Files      int     `json:"files"`
+	Percentage float64 `json:"percentage"`
+}
+
+func languageBreakdown(tree *object.Tree) ([]LanguageStat, error) {
+	stats := map[string]*LanguageStat{}
+	var total int64
+
+	err := tree.Files().ForEach(func(f *object.File) error {
+		if isIgnoredPath(f.Name) {
+			return nil
+		}
+		lang := languageForPath(f.Name)
+		if lang == "" {
+			return nil
+		}
+		if stats[lang] == nil {
+			stats[lang] = &LanguageStat{Language: lang}
+		}
+		size := f.Size
+		if lfsSize, ok := lfsPointerSize(f); ok {
+			size = lfsSize
+		}
+		stats[lang].Bytes += size
+		stats[lang].Files++
+		total += size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := make([]LanguageStat, 0, len(stats))
+	for _, stat := range stats {
+		if total > 0 {
+			stat.Percentage = float64(stat.Bytes) / float64(total) * 100
+		}
+		breakdown = append(breakdown, *stat)
+	}
+	sort.Slice(breakdown, func(i, j int) bool {
+		return languageRanksBefore(breakdown[i], breakdown[j])
+	})
+	return breakdown, nil
+}
+
+// languageRanksBefore orders by bytes, breaking ties by file count and then
+// alphabetically so the result is deterministic.
+func languageRanksBefore(a, b LanguageStat) bool {
+	if a.Bytes != b.Bytes {
+		return a.Bytes > b.Bytes
+	}
+	if a.Files != b.Files {
+		return a.Files > b.Files
+	}
+	return a.Language < b.Language
+}
+
+func headLanguages(repo *git.Repository) ([]LanguageStat, error) {
+	ref, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	return languageBreakdown(tree)
+}
+
+func primaryLanguage(breakdown []LanguageStat) string {
+	for _, stat := range breakdown {
+		if !dataLanguages[stat.Language] {
+			return stat.Language
+		}
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const defaultLargeFileBytes = 1 << 20
+
+type LargeFile struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+	Hash  string `json:"hash"`
+	// LFS marks a Git LFS pointer; Bytes is then the size it records.
+	LFS bool `json:"lfs,omitempty"`
+}
+
+type LargeFilesResponse struct {
+	Commit     string      `json:"commit"`
+	MinBytes   int64       `json:"minBytes"`
+	TotalFiles int         `json:"totalFiles"`
+	TotalBytes int64       `json:"totalBytes"`
+	Files      []LargeFile `json:"files"`
+}
+
+// LargeFilesHandler lists the blobs in the tip tree of a branch that are at
+// least minBytes, largest first. Sizes come from the object headers, so blob
+// contents are never read.
+func LargeFilesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	minBytes := int64(defaultLargeFileBytes)
+	if value := query.Get("minBytes"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			http.Error(w, "minBytes must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		minBytes = n
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	commit, err := tipCommit(r.Context(), repo, query.Get("branch"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read tree: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := LargeFilesResponse{Commit: commit.Hash.String(), MinBytes: minBytes, Files: []LargeFile{}}
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read tree: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !entry.Mode.IsFile() || entry.Mode == filemode.Symlink {
+			continue
+		}
+		size, err := repo.Storer.EncodedObjectSize(entry.Hash)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read size of %s: %v", name, err), http.StatusInternalServerError)
+			return
+		}
+		lfs := false
+		if size <= maxLFSPointerBytes {
+			if f, err := tree.TreeEntryFile(&entry); err == nil {
+				if lfsSize, ok := lfsPointerSize(f); ok {
+					size, lfs = lfsSize, true
+				}
+			}
+		}
+		resp.TotalFiles++
+		resp.TotalBytes += size
+		if size >= minBytes {
+			resp.Files = append(resp.Files, LargeFile{Path: name, Bytes: size, Hash: entry.Hash.String(), LFS: lfs})
+		}
+	}
+
+	sort.Slice(resp.Files, func(i, j int) bool {
+		if resp.Files[i].Bytes != resp.Files[j].Bytes {
+			return resp.Files[i].Bytes > resp.Files[j].Bytes
+		}
+		return resp.Files[i].Path < resp.Files[j].Path
+	})
+
+	writeJSON(w, r, resp)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Git LFS stores large files as small pointer blobs. The spec caps pointers
+// at 1024 bytes, so larger blobs are never read.
+const (
+	lfsPointerVersion  = "version https://git-lfs.github.com/spec/v1"
+	maxLFSPointerBytes = 1024
+	// lfsPointerLines is the line count of a pointer, which bounds the
+	// additions or deletions a pointer can contribute to a diff.
+	lfsPointerLines = 3
+)
+
+// parseLFSPointer returns the size recorded in an LFS pointer.
+func parseLFSPointer(r io.Reader) (int64, bool) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != lfsPointerVersion {
+		return 0, false
+	}
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "size ")
+		if !ok {
+			continue
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || size < 0 {
+			return 0, false
+		}
+		return size, true
+	}
+	return 0, false
+}
+
+// lfsPointerSize reports whether f is an LFS pointer and, if so, the size of
+// the file it stands for.
+func lfsPointerSize(f *object.File) (int64, bool) {
+	if f.Size > maxLFSPointerBytes {
+		return 0, false
+	}
+	reader, err := f.Reader()
+	if err != nil {
+		return 0, false
+	}
+	defer reader.Close()
+	return parseLFSPointer(io.LimitReader(reader, maxLFSPointerBytes))
+}
+
+// isLFSPointerIn reports whether name is an LFS pointer in tree. A nil tree,
+// as for the parent of a root commit, has no files.
+func isLFSPointerIn(tree *object.Tree, name string) bool {
+	if tree == nil {
+		return false
+	}
+	f, err := tree.File(name)
+	if err != nil {
+		return false
+	}
+	_, ok := lfsPointerSize(f)
+	return ok
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// maxLicenseBytes bounds how much of a license file is read; every license
+// identified here states itself well within it.
+const maxLicenseBytes = 64 << 10
+
+// unknownLicense is the SPDX value for a license file whose text is not
+// recognised.
+const unknownLicense = "NOASSERTION"
+
+type LicenseInfo struct {
+	SPDXID string `json:"spdxId"`
+	Path   string `json:"path"`
+}
+
+// licenseRules identify a license by phrases from its text, compared after
+// lowercasing and collapsing whitespace. They are tried in order, so the
+// more specific variants of a family come first.
+var licenseRules = []struct {
+	spdxID  string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"EPL-2.0", []string{"eclipse public license", "2.0"}},
+	{"BSL-1.0", []string{"boost software license"}},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+}
+
+// isLicenseFile matches the usual top-level names: LICENSE, LICENCE, COPYING
+// and UNLICENSE, with an optional suffix such as -MIT and a text extension.
+func isLicenseFile(name string) bool {
+	lower := strings.ToLower(name)
+	switch path.Ext(lower) {
+	case "", ".md", ".txt", ".rst":
+		lower = strings.TrimSuffix(lower, path.Ext(lower))
+	default:
+		return false
+	}
+	for _, prefix := range []string{"license", "licence", "copying", "unlicense"} {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func identifyLicense(text string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	for _, rule := range licenseRules {
+		matched := true
+		for _, phrase := range rule.phrases {
+			if !strings.Contains(normalized, phrase) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rule.spdxID
+		}
+	}
+	return unknownLicense
+}
+
+// detectLicense looks for a license file at the top of tree. When several
+// exist, an identified one wins, then the shortest name (LICENSE before
+// LICENSE-THIRD-PARTY). It returns nil when there is none.
+func detectLicense(tree *object.Tree) *LicenseInfo {
+	var candidates []LicenseInfo
+	for _, entry := range tree.Entries {
+		if !entry.Mode.IsFile() || !isLicenseFile(entry.Name) {
+			continue
+		}
+		f, err := tree.TreeEntryFile(&entry)
+		if err != nil {
+			continue
+		}
+		reader, err := f.Reader()
+		if err != nil {
+			continue
+		}
+		text, err := io.ReadAll(io.LimitReader(reader, maxLicenseBytes))
+		reader.Close()
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, LicenseInfo{SPDXID: identifyLicense(string(text)), Path: entry.Name})
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		iKnown, jKnown := candidates[i].SPDXID != unknownLicense, candidates[j].SPDXID != unknownLicense
+		if iKnown != jKnown {
+			return iKnown
+		}
+		return len(candidates[i].Path) < len(candidates[j].Path)
+	})
+	return &candidates[0]
+}
+
+func headLicense(repo *git.Repository) (*LicenseInfo, error) {
+	ref, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	return detectLicense(tree), nil
+}
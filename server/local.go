@@ -0,0 +1,96 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+var (
+	errLocalReposDisabled = errors.New("local repositories are disabled; set LOCAL_REPOS_BASE to enable them")
+	errLocalPathOutside   = errors.New("localPath must be inside the allowed base directory")
+	errLocalRepoConflict  = errors.New("the repository id is already taken")
+)
+
+// resolveLocalPath returns the symlink-free absolute form of localPath after
+// checking that it lies inside LOCAL_REPOS_BASE.
+func resolveLocalPath(localPath string) (string, error) {
+	base := os.Getenv("LOCAL_REPOS_BASE")
+	if base == "" {
+		return "", errLocalReposDisabled
+	}
+
+	base, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	base, err = filepath.EvalSymlinks(base)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := filepath.Abs(localPath)
+	if err != nil {
+		return "", err
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errLocalPathOutside
+	}
+	return path, nil
+}
+
+// localRepoID derives the storage id for a checkout from its exact path.
+// Unlike URLs, paths are not normalized: /srv/App and /srv/app, or foo and
+// foo.git, are different checkouts.
+func localRepoID(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".git")
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = "repo"
+	}
+	sum := sha256.Sum256([]byte(path))
+	return name + "-" + hex.EncodeToString(sum[:4])
+}
+
+// registerLocalRepo makes an existing checkout available under reposDir by
+// symlinking it, so every handler can open it like a cloned repository. It
+// returns the id and the resolved path the link points at.
+func registerLocalRepo(localPath string) (repoID, path string, err error) {
+	path, err = resolveLocalPath(localPath)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := git.PlainOpen(path); err != nil {
+		return "", "", err
+	}
+
+	repoID = localRepoID(path)
+	link := filepath.Join(reposDir, repoID)
+	err = os.Symlink(path, link)
+	if errors.Is(err, fs.ErrExist) {
+		err = checkLocalLink(link, path)
+	}
+	return repoID, path, err
+}
+
+// checkLocalLink makes sure the entry already at link registers path. Ids
+// keep just a short hash of the path, so a clone or another checkout's link
+// can sit under the same id; reusing it would serve the wrong repository.
+func checkLocalLink(link, path string) error {
+	if target, err := os.Readlink(link); err != nil || target != path {
+		return fmt.Errorf("%w: %s belongs to another repository", errLocalRepoConflict, filepath.Base(link))
+	}
+	return nil
+}
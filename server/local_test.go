@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// localCheckouts enables local registration under a temp base and creates
+// a repository at each of names inside it.
+func localCheckouts(t *testing.T, names ...string) map[string]string {
+	t.Helper()
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("LOCAL_REPOS_BASE", base)
+	paths := map[string]string{}
+	for _, name := range names {
+		path := filepath.Join(base, name)
+		if _, err := git.PlainInit(path, false); err != nil {
+			t.Fatal(err)
+		}
+		paths[name] = path
+	}
+	return paths
+}
+
+func TestRegisterLocalRepoDistinctPaths(t *testing.T) {
+	newFixtureRepo(t)
+	paths := localCheckouts(t, "App", "app", "foo", "foo.git")
+
+	ids := map[string]string{}
+	for name, path := range paths {
+		id, _, err := registerLocalRepo(path)
+		if err != nil {
+			t.Fatalf("register %s: %v", name, err)
+		}
+		if other, ok := ids[id]; ok {
+			t.Errorf("%s and %s share id %s", name, other, id)
+		}
+		ids[id] = name
+
+		again, _, err := registerLocalRepo(path)
+		if err != nil || again != id {
+			t.Errorf("registering %s again = %s, %v; want %s", name, again, err, id)
+		}
+		if target, err := os.Readlink(filepath.Join(reposDir, id)); err != nil || target != path {
+			t.Errorf("link for %s = %q, %v", name, target, err)
+		}
+	}
+}
+
+func TestRegisterLocalRepoConflict(t *testing.T) {
+	newFixtureRepo(t)
+	paths := localCheckouts(t, "app", "other")
+	id := localRepoID(paths["app"])
+
+	tests := []struct {
+		name  string
+		setup func(link string) error
+	}{
+		{name: "link to another checkout", setup: func(link string) error { return os.Symlink(paths["other"], link) }},
+		{name: "clone directory", setup: func(link string) error { return os.Mkdir(link, os.ModePerm) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := filepath.Join(reposDir, id)
+			if err := tt.setup(link); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.Remove(link) })
+
+			if _, _, err := registerLocalRepo(paths["app"]); !errors.Is(err, errLocalRepoConflict) {
+				t.Fatalf("registerLocalRepo = %v, want a conflict", err)
+			}
+			body := strings.NewReader(`{"localPath": "` + paths["app"] + `"}`)
+			if w := serve(RepoHandler, http.MethodPost, "/repo", body); w.Code != http.StatusConflict {
+				t.Errorf("POST /repo status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHelperLogsCarryRequestAttributes(t *testing.T) {
+	f := newFixtureRepo(t, fixtureCommit{Message: "Initial commit\n", Files: map[string]string{"README.md": "hello\n"}})
+	if err := os.WriteFile(filepath.Join(f.Path, repoConfigFile), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, nil))
+	handler := withRequestID(withLogger(logger, http.HandlerFunc(CommitsHandler)))
+	req := httptest.NewRequest(http.MethodGet, "/commits?repoId="+f.ID, nil)
+	req.Header.Set(requestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	var warned bool
+	for _, line := range bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n")) {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("decode log line %q: %v", line, err)
+		}
+		if record["msg"] != "Ignoring invalid repository config" {
+			continue
+		}
+		warned = true
+		if record["requestId"] != "req-123" || record["repoId"] != f.ID {
+			t.Errorf("warning lacks request attributes: %s", line)
+		}
+	}
+	if !warned {
+		t.Errorf("no config warning logged:\n%s", out.String())
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+type identity struct {
+	Name  string
+	Email string
+}
+
+type mailmap struct {
+	byEmail     map[string]identity
+	byNameEmail map[string]identity
+}
+
+func mailmapKey(name, email string) string {
+	return strings.ToLower(name) + "\x00" + strings.ToLower(email)
+}
+
+// loadMailmap reads .mailmap from the HEAD tree. A missing or unreadable
+// file yields an empty mailmap so callers can always resolve through it.
+func loadMailmap(repo *git.Repository) *mailmap {
+	m := &mailmap{
+		byEmail:     map[string]identity{},
+		byNameEmail: map[string]identity{},
+	}
+
+	ref, err := repo.Head()
+	if err != nil {
+		return m
+	}
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return m
+	}
+	file, err := commit.File(".mailmap")
+	if err != nil {
+		return m
+	}
+	contents, err := file.Contents()
+	if err != nil {
+		return m
+	}
+
+	m.parse(contents)
+	return m
+}
+
+func (m *mailmap) parse(contents string) {
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		var names, emails []string
+		for {
+			open := strings.Index(line, "<")
+			if open < 0 {
+				break
+			}
+			end := strings.Index(line[open:], ">")
+			if end < 0 {
+				break
+			}
+			names = append(names, strings.TrimSpace(line[:open]))
+			emails = append(emails, strings.TrimSpace(line[open+1:open+end]))
+			line = line[open+end+1:]
+		}
+
+		switch len(emails) {
+		case 1:
+			// Proper Name <commit@email>
+			m.byEmail[strings.ToLower(emails[0])] = identity{Name: names[0]}
+		case 2:
+			// [Proper Name] <proper@email> [Commit Name] <commit@email>
+			proper := identity{Name: names[0], Email: emails[0]}
+			if names[1] != "" {
+				m.byNameEmail[mailmapKey(names[1], emails[1])] = proper
+			} else {
+				m.byEmail[strings.ToLower(emails[1])] = proper
+			}
+		}
+	}
+}
+
+func (m *mailmap) resolve(name, email string) identity {
+	resolved := identity{Name: name, Email: email}
+
+	entry, ok := m.byNameEmail[mailmapKey(name, email)]
+	if !ok {
+		entry, ok = m.byEmail[strings.ToLower(email)]
+	}
+	if !ok {
+		return resolved
+	}
+
+	if entry.Name != "" {
+		resolved.Name = entry.Name
+	}
+	if entry.Email != "" {
+		resolved.Email = entry.Email
+	}
+	return resolved
+}
@@ -2,8 +2,9 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -17,28 +18,109 @@ import (
 )
 
 func main() {
-	if _, err := os.Stat("repos"); os.IsNotExist(err) {
-		err := os.Mkdir("repos", os.ModePerm)
+	logger := newLogger()
+	slog.SetDefault(logger)
+
+	rules, err := compileIdentityRules(os.Getenv("IDENTITY_RULES"))
+	if err != nil {
+		logger.Error("Invalid identity rules", "error", err)
+		os.Exit(1)
+	}
+	identityRules = rules
+
+	patterns, err := compileRedactPatterns(os.Getenv("REDACT_PATTERNS"))
+	if err != nil {
+		logger.Error("Invalid redact patterns", "error", err)
+		os.Exit(1)
+	}
+	redactPatterns = patterns
+
+	if _, err := os.Stat(reposDir); os.IsNotExist(err) {
+		err := os.MkdirAll(reposDir, os.ModePerm)
 		if err != nil {
-			log.Fatal("Failed to create repos directory:", err)
+			logger.Error("Failed to create repos directory", "error", err)
+			os.Exit(1)
 		}
 	}
 
+	installGitHTTPClient()
+
+	http.HandleFunc("/healthz", HealthHandler)
+	http.HandleFunc("/readyz", ReadyHandler)
 	http.HandleFunc("/repo", RepoHandler)
+	http.HandleFunc("/repo/config", RepoConfigHandler)
+	http.HandleFunc("/repo/clone", CancelCloneHandler)
+	http.HandleFunc("/repos/orphans", OrphansHandler)
+	http.HandleFunc("/verify", VerifyHandler)
+	http.HandleFunc("/message-quality", MessageQualityHandler)
+	http.HandleFunc("/active-contributors", ActiveContributorsHandler)
+	http.HandleFunc("/recent", RecentHandler)
+	http.HandleFunc("/velocity", VelocityHandler)
+	http.HandleFunc("/cadence-by-author", CadenceByAuthorHandler)
+	http.HandleFunc("/commit-intervals", CommitIntervalsHandler)
+	http.HandleFunc("/sessions", SessionsHandler)
+	http.HandleFunc("/onboarding", OnboardingHandler)
+	http.HandleFunc("/contributions-timeseries", ContributionsTimeseriesHandler)
+	http.HandleFunc("/graph", GraphHandler)
+	http.HandleFunc("/summary", SummaryHandler)
+	http.HandleFunc("/org/summary", OrgSummaryHandler)
+	http.HandleFunc("/diff/range", RangeDiffHandler)
+	http.HandleFunc("/commit", CommitHandler)
+	http.HandleFunc("/commit/patch", CommitPatchHandler)
+	http.HandleFunc("/stream/files", StreamFilesHandler)
+	http.HandleFunc("/unreleased", UnreleasedHandler)
+	http.HandleFunc("/releases", ReleasesHandler)
+	http.HandleFunc("/anomalies", AnomaliesHandler)
+	http.HandleFunc("/ownership-trend", OwnershipTrendHandler)
+	http.HandleFunc("/commits", CommitsHandler)
+	http.HandleFunc("/feed", FeedHandler)
+	http.HandleFunc("/commits/batch", CommitsBatchHandler)
+	http.HandleFunc("/commits/touching", CommitsTouchingHandler)
+	http.HandleFunc("/pickaxe", PickaxeHandler)
+	http.HandleFunc("/merges", MergesHandler)
+	http.HandleFunc("/count", CountHandler)
+	http.HandleFunc("/files", FileModificationsHandler)
+	http.HandleFunc("/manifest", ManifestHandler)
+	http.HandleFunc("/file/activity", FileActivityHandler)
+	http.HandleFunc("/large-files", LargeFilesHandler)
+	http.HandleFunc("/code-age", CodeAgeHandler)
+	http.HandleFunc("/dashboard", DashboardHandler)
+	http.HandleFunc("/churn-by-language", ChurnByLanguageHandler)
+	http.HandleFunc("/churn-anomalies", ChurnAnomaliesHandler)
+	http.HandleFunc("/depth-distribution", DepthDistributionHandler)
+	http.HandleFunc("/write-once", WriteOnceHandler)
+	http.HandleFunc("/branches", BranchesHandler)
+	http.HandleFunc("/branch-heads", BranchHeadsHandler)
+	http.HandleFunc("/remotes", RemotesHandler)
+	http.HandleFunc("/status", StatusHandler)
+	http.HandleFunc("/date-skew", DateSkewHandler)
+	http.HandleFunc("/poll", PollHandler)
 
 	handler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173"},
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-API-Key", requestIDHeader, idempotencyKeyHeader},
+		ExposedHeaders:   []string{requestIDHeader, shallowBoundaryHeader, totalCountHeader, "Link", patchTruncatedHeader},
 		AllowCredentials: true,
-	}).Handler(http.DefaultServeMux)
+	}).Handler(withRequestID(withLogger(logger, withEnvelope(withAPIKeyAuth(apiKeysFromEnv(), http.DefaultServeMux)))))
 
-	fmt.Println("Server is running on http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", handler))
+	logger.Info("Server is running", "addr", "http://localhost:8080")
+	if err := newServer(":8080", handler).ListenAndServe(); err != nil {
+		logger.Error("Server stopped", "error", err)
+		os.Exit(1)
+	}
 }
 
+var maxRequestBodyBytes = int64(envInt("MAX_REQUEST_BODY_BYTES", 1<<20))
+
 type CloneRequest struct {
-	RepoURL string `json:"repoUrl"`
+	RepoURL       string `json:"repoUrl"`
+	SSHPrivateKey string `json:"sshPrivateKey,omitempty"`
+	SSHPassphrase string `json:"sshPassphrase,omitempty"`
+	Bare          bool   `json:"bare,omitempty"`
+	LocalPath     string `json:"localPath,omitempty"`
+	// Refs limits a clone to these branches, ref names or refspecs.
+	Refs []string `json:"refs,omitempty"`
 }
 
 func RepoHandler(w http.ResponseWriter, r *http.Request) {
@@ -47,139 +129,343 @@ func RepoHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req CloneRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	start := time.Now()
+
+	order, err := parseCommitOrder(r.URL.Query().Get("order"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	parts := strings.Split(req.RepoURL, "/")
-	repoID := strings.TrimSuffix(parts[len(parts)-1], ".git")
+	commitOpts, err := parseCommitOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	repoPath := filepath.Join("repos", repoID)
+	// Diffing every commit is most of the cost of the stream, so clients
+	// that only draw a timeline can turn it off.
+	includeStats := r.URL.Query().Get("includeStats") != "false"
 
-	sendSSEMessage(w, "status", map[string]interface{}{
-		"message": "Starting repository processing",
-		"repoId":  repoID,
-	})
+	var req CloneRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
 
-	var repo *git.Repository
-	var err error
+	auth, err := cloneAuth(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
-		sendSSEMessage(w, "status", map[string]interface{}{
-			"message": "Cloning repository",
-			"repoUrl": req.RepoURL,
-		})
+	if (req.RepoURL == "") == (req.LocalPath == "") {
+		http.Error(w, "Exactly one of repoUrl or localPath is required", http.StatusBadRequest)
+		return
+	}
 
-		repo, err = git.PlainClone(repoPath, false, &git.CloneOptions{
-			URL:      req.RepoURL,
-			Progress: os.Stdout,
-		})
+	refSpecs, err := parseRefSpecs(req.Refs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(refSpecs) > 0 && req.LocalPath != "" {
+		http.Error(w, "refs can only be used with repoUrl", http.StatusBadRequest)
+		return
+	}
+
+	repoID, localPath := repoIDForURL(req.RepoURL), ""
+	if req.LocalPath != "" {
+		repoID, localPath, err = registerLocalRepo(req.LocalPath)
 		if err != nil {
-			sendSSEMessage(w, "error", map[string]string{
-				"message": fmt.Sprintf("Clone failed: %v", err),
-			})
+			status := http.StatusBadRequest
+			if errors.Is(err, errLocalRepoConflict) {
+				status = http.StatusConflict
+			}
+			http.Error(w, fmt.Sprintf("Failed to register local repository: %v", err), status)
 			return
 		}
+	}
 
-		sendSSEMessage(w, "status", map[string]interface{}{
-			"message": "Repository cloned successfully",
-			"repoId":  repoID,
-		})
-	} else {
-		sendSSEMessage(w, "status", map[string]interface{}{
-			"message": "Repository already exists, opening existing repository",
+	idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+	if idempotencyKey != "" && !validRequestID(idempotencyKey) {
+		http.Error(w, "Invalid Idempotency-Key", http.StatusBadRequest)
+		return
+	}
+
+	release, ok := acquireStream(w, repoID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	fingerprint := fmt.Sprintf("%s|%t|%s", repoID, req.Bare, strings.Join(req.Refs, ","))
+	pending, owner, err := cloneRequests.begin(idempotencyKey, fingerprint)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Only a clone needs a slot. Opening an existing repository does not,
+	// and neither does waiting for an earlier request with the same key.
+	cloneNeeded := func() bool {
+		_, err := os.Stat(filepath.Join(reposDir, repoID))
+		return os.IsNotExist(err)
+	}
+	var releaseSlot func()
+	if owner && cloneNeeded() {
+		if releaseSlot, err = acquireCloneSlot(r.Context()); err != nil {
+			cloneRequests.abandon(pending)
+			rejectStream(w, "Too many concurrent clones")
+			return
+		}
+		defer releaseSlot()
+	}
+
+	logger := loggerFrom(r.Context()).With("repoId", repoID)
+	setSSEHeaders(w)
+
+	sendSSEMessage(w, r, "status", map[string]interface{}{
+		"message":   "Starting repository processing",
+		"repoId":    repoID,
+		"requestId": requestIDFrom(r.Context()),
+	})
+
+	for !owner {
+		sendSSEMessage(w, r, "status", map[string]interface{}{
+			"message": "Waiting for an earlier request with the same Idempotency-Key",
 			"repoId":  repoID,
 		})
-
-		repo, err = git.PlainOpen(repoPath)
+		err := pending.wait(r.Context())
+		if errors.Is(err, errCloneAbandoned) {
+			// The earlier client went away mid-clone, so this request
+			// may take the key over.
+			pending, owner, err = cloneRequests.begin(idempotencyKey, fingerprint)
+			if err == nil {
+				continue
+			}
+		}
 		if err != nil {
-			sendSSEMessage(w, "error", map[string]string{
-				"message": fmt.Sprintf("Failed to open repository: %v", err),
+			sendSSEMessage(w, r, "error", map[string]string{
+				"message": fmt.Sprintf("Earlier request with the same Idempotency-Key failed: %v", err),
 			})
 			return
 		}
+		break
+	}
+	// A waiter that took the key over clones after all.
+	if owner && releaseSlot == nil && cloneNeeded() {
+		if releaseSlot, err = acquireCloneSlot(r.Context()); err != nil {
+			cloneRequests.abandon(pending)
+			sendSSEMessage(w, r, "error", map[string]string{
+				"message": "Too many concurrent clones",
+			})
+			return
+		}
+		defer releaseSlot()
 	}
 
-	ref, err := repo.Head()
+	repo, cloned, err := cloneOrOpen(r.Context(), req.RepoURL, repoID, cloneOptions{
+		Auth:      auth,
+		Bare:      req.Bare,
+		Refs:      refSpecs,
+		LocalPath: localPath,
+		Progress: newProgressWriter(func(p CloneProgress) {
+			sendSSEMessage(w, r, "progress", p)
+		}),
+		BeforeClone: func() {
+			sendSSEMessage(w, r, "status", map[string]interface{}{
+				"message": "Cloning repository",
+				"repoUrl": req.RepoURL,
+			})
+			logger.Info("Cloning repository", "bare", req.Bare)
+		},
+	})
+	if owner {
+		cloneRequests.finish(pending, err)
+	}
 	if err != nil {
-		sendSSEMessage(w, "error", map[string]string{
-			"message": fmt.Sprintf("Failed to get HEAD reference: %v", err),
-		})
+		if cloned {
+			logger.Error("Clone failed", "error", err)
+			sendSSEMessage(w, r, "error", map[string]string{
+				"message": cloneErrorMessage(err),
+			})
+		} else if errors.Is(err, errOriginMismatch) || errors.Is(err, errLocalRepoConflict) {
+			logger.Error("Existing repository does not match the requested URL", "error", err)
+			sendSSEMessage(w, r, "error", map[string]string{
+				"message": fmt.Sprintf("Refusing to reuse repository %s: %v. Move or remove its directory, then retry", repoID, err),
+			})
+		} else {
+			logger.Error("Failed to open repository", "error", err)
+			sendSSEMessage(w, r, "error", map[string]string{
+				"message": fmt.Sprintf("Failed to open repository: %v", err),
+			})
+		}
 		return
 	}
 
-	sendSSEMessage(w, "status", map[string]string{
+	gcRan := false
+	if cloned {
+		logger.Info("Repository cloned")
+		repoUpdates.notify(repoID)
+		cloneStatus := map[string]interface{}{
+			"message": "Repository cloned successfully",
+			"repoId":  repoID,
+			"bare":    req.Bare,
+		}
+		if len(refSpecs) > 0 {
+			if fetched, err := fetchedRefs(repo, refSpecs); err == nil {
+				cloneStatus["refs"] = refNames(fetched)
+			}
+		}
+		sendSSEMessage(w, r, "status", cloneStatus)
+
+		if gcAfterClone {
+			sendSSEMessage(w, r, "status", map[string]string{
+				"message": "Optimizing repository objects",
+			})
+			repacked, err := repackRepository(repo, repoID)
+			if repacked != nil {
+				repo = repacked
+			}
+			if err != nil {
+				logger.Warn("Repack after clone failed", "error", err)
+			} else {
+				gcRan = true
+			}
+		}
+	} else {
+		sendSSEMessage(w, r, "status", map[string]interface{}{
+			"message": "Repository already exists, opening existing repository",
+			"repoId":  repoID,
+		})
+	}
+
+	filter.bind(r.Context(), repo)
+
+	sendSSEMessage(w, r, "status", map[string]string{
 		"message": "Fetching branches",
 	})
 
 	branches, err := getBranches(repo)
 	if err != nil {
-		sendSSEMessage(w, "error", map[string]string{
+		sendSSEMessage(w, r, "error", map[string]string{
 			"message": fmt.Sprintf("Failed to get branches: %v", err),
 		})
 	} else {
-		sendSSEMessage(w, "branches", branches)
+		sendSSEMessage(w, r, "branches", branches)
 	}
 
-	sendSSEMessage(w, "status", map[string]string{
+	sendSSEMessage(w, r, "status", map[string]string{
 		"message": "Fetching commits history",
 	})
 
-	iter, err := repo.Log(&git.LogOptions{From: ref.Hash()})
-	if err != nil {
-		sendSSEMessage(w, "error", map[string]string{
-			"message": fmt.Sprintf("Failed to get commit logs: %v", err),
-		})
-		return
+	tagCount := 0
+	if tags, err := listTags(repo); err == nil {
+		tagCount = len(tags)
 	}
 
-	err = iter.ForEach(func(c *object.Commit) error {
-		commitData := map[string]interface{}{
-			"hash":    c.Hash.String(),
-			"author":  c.Author.Name,
-			"email":   c.Author.Email,
-			"message": c.Message,
-			"date":    c.Author.When.Format(time.RFC3339),
-		}
-
-		stats, err := c.Stats()
-		if err == nil {
-			modifications := []map[string]interface{}{}
-			for _, stat := range stats {
-				modifications = append(modifications, map[string]interface{}{
-					"file":      stat.Name,
-					"additions": stat.Addition,
-					"deletions": stat.Deletion,
-				})
+	authors := newAuthorCounter(loadMailmap(repo))
+	commitCount := 0
+	runningAdditions, runningDeletions := 0, 0
+
+	stream := newSSEBatcher(w)
+	err = forEachCommit(r.Context(), repo, "", filter.logOptions(), order, func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		commitCount++
+		authors.add(c.Author)
+
+		commit := streamedCommit{Commit: newCommit(c, commitOpts)}
+		if includeStats {
+			totals := &streamedTotals{}
+			if modifications, err := commitModifications(c); err == nil {
+				commit.setModifications(modifications)
+				for _, mod := range modifications {
+					totals.TotalAdditions += mod.Additions
+					totals.TotalDeletions += mod.Deletions
+				}
 			}
-			commitData["modifications"] = modifications
+			runningAdditions += totals.TotalAdditions
+			runningDeletions += totals.TotalDeletions
+			totals.RunningAdditions = runningAdditions
+			totals.RunningDeletions = runningDeletions
+			commit.streamedTotals = totals
 		}
 
-		sendSSEMessage(w, "commit", commitData)
+		sendSSEMessage(stream, r, "commit", commit)
 
-		time.Sleep(100 * time.Millisecond)
+		if streamCommitDelay > 0 {
+			time.Sleep(streamCommitDelay)
+		}
 		return nil
 	})
+	stream.Close()
 
 	if err != nil {
-		sendSSEMessage(w, "error", map[string]string{
+		sendSSEMessage(w, r, "error", map[string]string{
 			"message": fmt.Sprintf("Error processing commits: %v", err),
 		})
 	}
 
-	sendSSEMessage(w, "complete", map[string]string{
-		"message": "Repository analysis complete",
-		"repoId":  repoID,
+	sendSSEMessage(w, r, "complete", map[string]interface{}{
+		"message":      "Repository analysis complete",
+		"repoId":       repoID,
+		"commits":      commitCount,
+		"branches":     len(branches),
+		"tags":         tagCount,
+		"contributors": authors.len(),
+		"additions":    runningAdditions,
+		"deletions":    runningDeletions,
+		"stats":        includeStats,
+		"gc":           gcRan,
+		"elapsedMs":    time.Since(start).Milliseconds(),
 	})
 }
 
+// streamedCommit is the payload of a commit event on the clone stream. The
+// totals are left out when the stream was started with includeStats=false.
+type streamedCommit struct {
+	Commit
+	*streamedTotals
+}
+
+// streamedTotals are a commit's line counts. The running totals cover every
+// commit sent so far, including this one.
+type streamedTotals struct {
+	TotalAdditions   int `json:"totalAdditions"`
+	TotalDeletions   int `json:"totalDeletions"`
+	RunningAdditions int `json:"runningAdditions"`
+	RunningDeletions int `json:"runningDeletions"`
+}
+
+// decodeJSONBody decodes a size-limited JSON request body into v, writing a
+// 413 or 400 response and returning false when it cannot.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return false
+		}
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// getBranches lists the branch names history endpoints accept: local
+// branches and remote-tracking ones, each once. A fresh clone has only its
+// default branch locally, so the rest are known only as remote-tracking
+// branches. Those on origin are listed without the "origin/" prefix, since
+// resolveBranch falls back to origin for a bare name.
 func getBranches(repo *git.Repository) ([]string, error) {
 	branches := []string{}
 	refs, err := repo.References()
@@ -187,20 +473,43 @@ func getBranches(repo *git.Repository) ([]string, error) {
 		return nil, err
 	}
 
-	refs.ForEach(func(ref *plumbing.Reference) error {
-		if ref.Name().IsBranch() {
-			branches = append(branches, ref.Name().Short())
+	seen := map[string]bool{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		switch {
+		case ref.Name().IsBranch():
+		case ref.Name().IsRemote() && ref.Type() == plumbing.HashReference:
+			name = strings.TrimPrefix(name, git.DefaultRemoteName+"/")
+		default:
+			return nil
+		}
+		if !seen[name] {
+			seen[name] = true
+			branches = append(branches, name)
 		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	return branches, nil
 }
 
-func sendSSEMessage(w http.ResponseWriter, eventType string, data interface{}) {
+// setSSEHeaders starts an event stream, which also lifts the server
+// timeouts for the rest of the response.
+func setSSEHeaders(w http.ResponseWriter) {
+	clearDeadlines(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+}
+
+func sendSSEMessage(w http.ResponseWriter, r *http.Request, eventType string, data interface{}) {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
-		log.Printf("Error marshaling JSON for SSE: %v", err)
+		loggerFrom(r.Context()).Error("Error marshaling JSON for SSE", "event", eventType, "error", err)
 		return
 	}
 
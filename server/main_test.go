@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"golang.org/x/sync/semaphore"
+)
+
+var errRefsUnreadable = errors.New("reference storage unreadable")
+
+// failingRefs is a storage whose reference listing fails partway through.
+type failingRefs struct {
+	storage.Storer
+}
+
+func (failingRefs) IterReferences() (storer.ReferenceIter, error) {
+	return failingRefIter{}, nil
+}
+
+type failingRefIter struct{}
+
+func (failingRefIter) Next() (*plumbing.Reference, error) { return nil, errRefsUnreadable }
+
+func (failingRefIter) ForEach(func(*plumbing.Reference) error) error { return errRefsUnreadable }
+
+func (failingRefIter) Close() {}
+
+func TestGetBranchesIterationError(t *testing.T) {
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err = git.Open(failingRefs{repo.Storer}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	branches, err := getBranches(repo)
+	if !errors.Is(err, errRefsUnreadable) {
+		t.Fatalf("getBranches = %v, %v; want the iteration error", branches, err)
+	}
+}
+
+func TestRepoHandlerBranchesError(t *testing.T) {
+	newFixtureRepo(t, fixtureCommit{Message: "Initial commit\n", Files: map[string]string{"a.txt": "a\n"}})
+
+	previousOpen, previousDelay := openRepository, streamCommitDelay
+	openRepository = func(path string) (*git.Repository, error) {
+		repo, err := git.PlainOpen(path)
+		if err != nil {
+			return nil, err
+		}
+		return git.Open(failingRefs{repo.Storer}, nil)
+	}
+	streamCommitDelay = 0
+	t.Cleanup(func() { openRepository, streamCommitDelay = previousOpen, previousDelay })
+
+	body := strings.NewReader(`{"repoUrl": "` + fixtureURL + `"}`)
+	w := serve(RepoHandler, http.MethodPost, "/repo", body)
+
+	var sawError, sawBranches bool
+	for _, event := range sseEvents(w.Body.String()) {
+		switch event[0] {
+		case "error":
+			if !strings.Contains(event[1], "Failed to get branches: reference storage unreadable") {
+				t.Errorf("unexpected error event: %s", event[1])
+			}
+			sawError = true
+		case "branches":
+			sawBranches = true
+		}
+	}
+	if !sawError {
+		t.Fatalf("no error event for the failing branch listing:\n%s", w.Body)
+	}
+	if sawBranches {
+		t.Errorf("branches event sent although listing failed")
+	}
+}
+
+func TestDecodeJSONBody(t *testing.T) {
+	previous := maxRequestBodyBytes
+	maxRequestBodyBytes = 64
+	t.Cleanup(func() { maxRequestBodyBytes = previous })
+
+	tests := []struct {
+		name        string
+		body        string
+		wantOK      bool
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "within limit", body: `{"repoUrl": "https://example.com/a.git"}`, wantOK: true, wantStatus: http.StatusOK},
+		{name: "over limit", body: `{"repoUrl": "https://example.com/` + strings.Repeat("a", 64) + `.git"}`, wantStatus: http.StatusRequestEntityTooLarge, wantMessage: "Request body too large"},
+		{name: "malformed", body: `{"repoUrl":`, wantStatus: http.StatusBadRequest, wantMessage: "Invalid request payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/repo", strings.NewReader(tt.body))
+			var req CloneRequest
+			if ok := decodeJSONBody(w, r, &req); ok != tt.wantOK {
+				t.Fatalf("decodeJSONBody = %t, want %t", ok, tt.wantOK)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tt.wantMessage {
+				t.Errorf("message = %q, want %q", got, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestRepoHandlerBodyTooLarge(t *testing.T) {
+	previous := maxRequestBodyBytes
+	maxRequestBodyBytes = 64
+	t.Cleanup(func() { maxRequestBodyBytes = previous })
+
+	body := strings.NewReader(`{"repoUrl": "https://example.com/` + strings.Repeat("a", 128) + `.git"}`)
+	w := serve(RepoHandler, http.MethodPost, "/repo", body)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusRequestEntityTooLarge, w.Body)
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != "Request body too large" {
+		t.Errorf("message = %q", got)
+	}
+}
+
+// exhaustCloneSlots leaves no clone slot free for the rest of the test, and
+// makes waiting for one fail quickly.
+func exhaustCloneSlots(t *testing.T) {
+	previousSlots, previousTimeout := cloneSlots, cloneSlotTimeout
+	cloneSlots = semaphore.NewWeighted(1)
+	if err := cloneSlots.Acquire(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	cloneSlotTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { cloneSlots, cloneSlotTimeout = previousSlots, previousTimeout })
+}
+
+func TestRepoHandlerIdempotencyBeforeCloneSlot(t *testing.T) {
+	newFixtureRepo(t)
+	exhaustCloneSlots(t)
+	const repoURL = "https://example.com/acme/missing.git"
+
+	t.Run("malformed key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/repo", strings.NewReader(`{"repoUrl": "`+repoURL+`"}`))
+		req.Header.Set(idempotencyKeyHeader, "not a key")
+		w := httptest.NewRecorder()
+		RepoHandler(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+		}
+	})
+
+	t.Run("duplicate waits without a slot", func(t *testing.T) {
+		const key = "clone-slot-test"
+		fingerprint := fmt.Sprintf("%s|%t|%s", repoIDForURL(repoURL), false, "")
+		original, owner, err := cloneRequests.begin(key, fingerprint)
+		if err != nil || !owner {
+			t.Fatalf("begin = %t, %v", owner, err)
+		}
+		t.Cleanup(func() {
+			cloneRequests.mu.Lock()
+			delete(cloneRequests.entries, key)
+			cloneRequests.mu.Unlock()
+		})
+		// Finish the original only after the duplicate would have given up
+		// on a clone slot.
+		timer := time.AfterFunc(10*cloneSlotTimeout, func() {
+			cloneRequests.finish(original, errors.New("remote hung up"))
+		})
+		defer timer.Stop()
+
+		req := httptest.NewRequest(http.MethodPost, "/repo", strings.NewReader(`{"repoUrl": "`+repoURL+`"}`))
+		req.Header.Set(idempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		RepoHandler(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+		events := sseEvents(w.Body.String())
+		last := events[len(events)-1]
+		if last[0] != "error" || !strings.Contains(last[1], "Earlier request with the same Idempotency-Key failed: remote hung up") {
+			t.Errorf("last event = %v, want the original's failure:\n%s", last, w.Body)
+		}
+	})
+}
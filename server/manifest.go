@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// maxManifestPage caps the files one /manifest response lists; larger trees
+// are paged with offset.
+var maxManifestPage = envInt("MANIFEST_MAX_FILES", 10000)
+
+type ManifestEntry struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+	// Mode is the git file mode in octal, such as 0100644 or 0120000 for a
+	// symlink.
+	Mode string `json:"mode"`
+}
+
+type Manifest struct {
+	Commit string          `json:"commit"`
+	Tree   string          `json:"tree"`
+	Files  []ManifestEntry `json:"files"`
+}
+
+// ManifestHandler lists every file at ref (the branch tip by default) with
+// its blob hash, size and mode, recursively and in tree order, so two refs
+// can be compared client-side or a file index built. Submodules are not
+// files and are left out. path= and ignore= narrow the list as elsewhere.
+// limit defaults to, and is capped by, MANIFEST_MAX_FILES; the rest is
+// reached with offset and the Link header.
+func ManifestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := maxManifestPage
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxManifestPage)
+	}
+	offset, ok := parseOffset(r)
+	if !ok {
+		http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	var c *object.Commit
+	if ref := query.Get("ref"); ref != "" {
+		c, err = resolveCommit(repo, ref)
+		if err != nil {
+			http.Error(w, revisionErrorMessage(ref, err), logErrorStatus(err))
+			return
+		}
+	} else {
+		c, err = tipCommit(r.Context(), repo, query.Get("branch"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+			return
+		}
+	}
+
+	tree, err := c.Tree()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read tree: %v", err), http.StatusInternalServerError)
+		return
+	}
+	files := []ManifestEntry{}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if !filter.includesFile(f.Name) {
+			return nil
+		}
+		files = append(files, ManifestEntry{
+			Path: f.Name,
+			Hash: f.Hash.String(),
+			Size: f.Size,
+			Mode: f.Mode.String(),
+		})
+		return r.Context().Err()
+	})
+	if r.Context().Err() != nil {
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read tree: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	start, end, links := offsetPage(r, len(files), offset, limit)
+	setPaginationHeaders(w, len(files), links)
+	writeJSON(w, r, Manifest{Commit: c.Hash.String(), Tree: c.TreeHash.String(), Files: files[start:end]})
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+const defaultMergesLimit = 50
+
+// prNumberPatterns find the pull request a merge commit came from, in the
+// message formats GitHub, GitLab and Bitbucket write, and the "(#123)"
+// suffix used by squash merges.
+var prNumberPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bpull request #(\d+)`),
+	regexp.MustCompile(`(?i)\bmerge request [^\s!]*!(\d+)`),
+	regexp.MustCompile(`\(#(\d+)\)`),
+}
+
+type Merge struct {
+	Commit
+	Parents []string `json:"parents"`
+	// PRNumber is parsed from the message and is nil when it names none.
+	PRNumber *int `json:"prNumber"`
+	// Introduced lists the commits the merge brought in: reachable from
+	// any parent but the first and not from the first, newest first.
+	Introduced []Commit `json:"introduced"`
+}
+
+func parsePRNumber(message string) *int {
+	for _, pattern := range prNumberPatterns {
+		if m := pattern.FindStringSubmatch(message); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				return &n
+			}
+		}
+	}
+	return nil
+}
+
+const (
+	fromFirstParent uint8 = 1 << iota
+	fromMergedParent
+)
+
+// mergedCommits returns the commits reachable from merge's other parents but
+// not its first one. It walks newest first from all parents at once, marking
+// what each side reaches, and stops as soon as only first-parent history is
+// left, so the cost depends on the size of the merged branch rather than on
+// the whole history. Like git, it relies on committer dates and can include
+// an extra commit if they are badly skewed. Parents missing from a shallow
+// clone end the walk on that side.
+func mergedCommits(repo *git.Repository, merge *object.Commit) ([]*object.Commit, error) {
+	flags := map[plumbing.Hash]uint8{}
+	queue := &commitHeap{}
+
+	push := func(hash plumbing.Hash, flag uint8) error {
+		if flags[hash]&flag == flag {
+			return nil
+		}
+		flags[hash] |= flag
+		c, err := repo.CommitObject(hash)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		heap.Push(queue, c)
+		return nil
+	}
+	// pending reports whether any queued commit has so far only been reached
+	// from the merged side; once none is left the walk can stop.
+	pending := func() bool {
+		for _, c := range *queue {
+			if flags[c.Hash] == fromMergedParent {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i, parent := range merge.ParentHashes {
+		flag := fromMergedParent
+		if i == 0 {
+			flag = fromFirstParent
+		}
+		if err := push(parent, flag); err != nil {
+			return nil, err
+		}
+	}
+
+	var candidates []*object.Commit
+	for queue.Len() > 0 && pending() {
+		c := heap.Pop(queue).(*object.Commit)
+		flag := flags[c.Hash]
+		if flag == fromMergedParent {
+			candidates = append(candidates, c)
+		}
+		for _, parent := range c.ParentHashes {
+			if err := push(parent, flag); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	introduced := candidates[:0]
+	for _, c := range candidates {
+		if flags[c.Hash] == fromMergedParent {
+			introduced = append(introduced, c)
+		}
+	}
+	return introduced, nil
+}
+
+// commitHeap pops the most recently committed commit first.
+type commitHeap []*object.Commit
+
+func (h commitHeap) Len() int { return len(h) }
+func (h commitHeap) Less(i, j int) bool {
+	return h[i].Committer.When.After(h[j].Committer.When)
+}
+func (h commitHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *commitHeap) Push(x interface{}) { *h = append(*h, x.(*object.Commit)) }
+func (h *commitHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// MergesHandler lists the newest merge commits with the commits each one
+// introduced and the pull request number from its message, which rebuilds a
+// PR-level history for merge-based workflows. Octopus merges count every
+// parent after the first as merged in.
+func MergesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := defaultMergesLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	commitOpts, err := parseCommitOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, r.URL.Query().Get("branch"), filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	merges := []Merge{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if c.NumParents() < 2 || !filter.matches(c) {
+			return nil
+		}
+
+		introduced, err := mergedCommits(repo, c)
+		if err != nil {
+			return err
+		}
+		merge := Merge{
+			Commit:     newCommit(c, commitOpts),
+			Parents:    make([]string, len(c.ParentHashes)),
+			PRNumber:   parsePRNumber(c.Message),
+			Introduced: make([]Commit, len(introduced)),
+		}
+		for i, parent := range c.ParentHashes {
+			merge.Parents[i] = parent.String()
+		}
+		for i, commit := range introduced {
+			merge.Introduced[i] = newCommit(commit, commitOpts)
+		}
+
+		merges = append(merges, merge)
+		if len(merges) == limit {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, merges)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var conventionalCommitPattern = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^()]*)\))?(!)?: (.+)$`)
+
+var lowQualitySubjects = map[string]bool{
+	"wip":     true,
+	"fix":     true,
+	"fixes":   true,
+	"fixed":   true,
+	"update":  true,
+	"updates": true,
+	"changes": true,
+	"misc":    true,
+	"tmp":     true,
+	"test":    true,
+	"asdf":    true,
+	".":       true,
+	"..":      true,
+	"...":     true,
+}
+
+type ConventionalCommit struct {
+	Type        string `json:"type"`
+	Scope       string `json:"scope,omitempty"`
+	Breaking    bool   `json:"breaking"`
+	Description string `json:"description"`
+}
+
+func commitSubject(message string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return strings.TrimSpace(subject)
+}
+
+func commitBody(message string) string {
+	_, body, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return strings.TrimSpace(body)
+}
+
+func parseConventionalCommit(message string) (ConventionalCommit, bool) {
+	m := conventionalCommitPattern.FindStringSubmatch(commitSubject(message))
+	if m == nil {
+		return ConventionalCommit{}, false
+	}
+
+	cc := ConventionalCommit{
+		Type:        strings.ToLower(m[1]),
+		Scope:       m[2],
+		Breaking:    m[3] == "!",
+		Description: m[4],
+	}
+	if strings.Contains(message, "BREAKING CHANGE:") || strings.Contains(message, "BREAKING-CHANGE:") {
+		cc.Breaking = true
+	}
+	return cc, true
+}
+
+type MessageQualityReport struct {
+	TotalCommits             int            `json:"totalCommits"`
+	AverageSubjectLength     float64        `json:"averageSubjectLength"`
+	WithBodyPercent          float64        `json:"withBodyPercent"`
+	SingleWordOrEmptyPercent float64        `json:"singleWordOrEmptyPercent"`
+	ConventionalCommits      int            `json:"conventionalCommits"`
+	ConventionalTypes        map[string]int `json:"conventionalTypes"`
+	LowQualityCommits        int            `json:"lowQualityCommits"`
+	LowQualityMessages       map[string]int `json:"lowQualityMessages"`
+}
+
+func MessageQualityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, "", filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	report := MessageQualityReport{
+		ConventionalTypes:  map[string]int{},
+		LowQualityMessages: map[string]int{},
+	}
+	subjectLength, withBody, singleWord := 0, 0, 0
+
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		report.TotalCommits++
+
+		subject := commitSubject(c.Message)
+		subjectLength += len([]rune(subject))
+		if commitBody(c.Message) != "" {
+			withBody++
+		}
+		if len(strings.Fields(subject)) <= 1 {
+			singleWord++
+		}
+
+		if cc, ok := parseConventionalCommit(c.Message); ok {
+			report.ConventionalCommits++
+			report.ConventionalTypes[cc.Type]++
+		}
+
+		normalized := strings.ToLower(subject)
+		if normalized == "" || lowQualitySubjects[normalized] {
+			report.LowQualityCommits++
+			report.LowQualityMessages[normalized]++
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if report.TotalCommits > 0 {
+		total := float64(report.TotalCommits)
+		report.AverageSubjectLength = float64(subjectLength) / total
+		report.WithBodyPercent = float64(withBody) / total * 100
+		report.SingleWordOrEmptyPercent = float64(singleWord) / total * 100
+	}
+
+	writeJSON(w, r, report)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type FirstCommit struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	// CanonicalID is the key the author is counted under, after the
+	// mailmap and IDENTITY_RULES. It is omitted with anonymize.
+	CanonicalID string `json:"canonicalId,omitempty"`
+	Hash        string `json:"hash"`
+	Date        string `json:"date"`
+
+	when time.Time
+}
+
+// OnboardingHandler lists when each author made their first commit, oldest
+// first, so a team's growth can be plotted. Authors are mailmap-resolved
+// the same way as the contributor counts. go-git only walks newest first,
+// so instead of an ascending walk every author's earliest commit by author
+// date is kept as the log goes by.
+func OnboardingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, r.URL.Query().Get("branch"), filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	authors := newAuthorCounter(loadMailmap(repo))
+	anonymize := parseAnonymize(r)
+	first := map[string]*FirstCommit{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		author, key := authors.resolve(c.Author)
+		if seen := first[key]; seen != nil && !c.Author.When.Before(seen.when) {
+			return nil
+		}
+		if anonymize {
+			author.Name, author.Email = pseudonym(author.Name, author.Email)
+		} else {
+			author.Email = redactEmail(author.Email)
+		}
+		first[key] = &FirstCommit{
+			Name:        author.Name,
+			Email:       author.Email,
+			CanonicalID: listedCanonicalID(key, anonymize),
+			Hash:        c.Hash.String(),
+			Date:        c.Author.When.Format(time.RFC3339),
+			when:        c.Author.When,
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	joined := make([]FirstCommit, 0, len(first))
+	for _, entry := range first {
+		joined = append(joined, *entry)
+	}
+	sort.Slice(joined, func(i, j int) bool {
+		if !joined[i].when.Equal(joined[j].when) {
+			return joined[i].when.Before(joined[j].when)
+		}
+		return joined[i].Name < joined[j].Name
+	})
+
+	writeJSON(w, r, joined)
+}
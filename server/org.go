@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+const (
+	maxOrgRepos       = 50
+	defaultOrgTopSize = 10
+)
+
+// orgSummaryWorkers bounds how many repositories /org/summary walks at once.
+var orgSummaryWorkers = envInt("ORG_SUMMARY_WORKERS", 4)
+
+type OrgSummaryRequest struct {
+	RepoIDs []string `json:"repoIds"`
+}
+
+type OrgRepoSummary struct {
+	RepoID  string   `json:"repoId"`
+	Summary *Summary `json:"summary,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+type OrgSummary struct {
+	Repos                int                `json:"repos"`
+	FailedRepos          int                `json:"failedRepos"`
+	TotalCommits         int                `json:"totalCommits"`
+	Contributors         int                `json:"contributors"`
+	ExcludedContributors int                `json:"excludedContributors"`
+	TopContributors      []ContributorCount `json:"topContributors"`
+	Summaries            []OrgRepoSummary   `json:"summaries"`
+}
+
+// OrgSummaryHandler builds the summary of every requested repository on a
+// small worker pool and rolls them up. Contributors are merged across
+// repositories by their mailmap-resolved email. A repository that cannot be
+// opened or summarised only fails its own entry.
+func OrgSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	top := defaultOrgTopSize
+	if value := r.URL.Query().Get("top"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "top must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	minCommits, err := parseMinCommits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req OrgSummaryRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if len(req.RepoIDs) == 0 {
+		http.Error(w, "repoIds is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.RepoIDs) > maxOrgRepos {
+		http.Error(w, fmt.Sprintf("at most %d repoIds are allowed per request", maxOrgRepos), http.StatusBadRequest)
+		return
+	}
+
+	summaries := make([]OrgRepoSummary, len(req.RepoIDs))
+	counters := make([]*authorCounter, len(req.RepoIDs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < max(1, min(orgSummaryWorkers, len(req.RepoIDs))); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				summaries[j], counters[j] = orgRepoSummary(r.Context(), req.RepoIDs[j], filter, minCommits)
+			}
+		}()
+	}
+	for i := range req.RepoIDs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	resp := OrgSummary{Repos: len(req.RepoIDs), Summaries: summaries}
+	authors := newAuthorCounter(nil)
+	authors.anonymize = parseAnonymize(r)
+	for i, summary := range summaries {
+		if summary.Error != "" {
+			resp.FailedRepos++
+			continue
+		}
+		resp.TotalCommits += summary.Summary.TotalCommits
+		authors.merge(counters[i])
+	}
+	contributors, excluded := authors.regular(minCommits)
+	resp.Contributors = len(contributors)
+	resp.ExcludedContributors = excluded
+	if len(contributors) > top {
+		contributors = contributors[:top]
+	}
+	resp.TopContributors = contributors
+
+	writeJSON(w, r, resp)
+}
+
+func orgRepoSummary(ctx context.Context, repoID string, filter commitFilter, minCommits int) (OrgRepoSummary, *authorCounter) {
+	ctx = contextWithLogger(ctx, loggerFrom(ctx).With("repoId", repoID))
+	entry := OrgRepoSummary{RepoID: repoID}
+	repo, resolved, err := getRepo(repoID)
+	if err != nil {
+		entry.Error = repoErrorMessage(err)
+		return entry, nil
+	}
+	filter.bind(ctx, repo)
+
+	summary, authors, err := buildSummary(ctx, repo, resolved, filter, minCommits)
+	if err != nil {
+		entry.Error = fmt.Sprintf("failed to build summary: %v", err)
+		return entry, nil
+	}
+	entry.Summary = &summary
+	return entry, authors
+}
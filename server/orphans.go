@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+type OrphanedRepo struct {
+	RepoID string `json:"repoId"`
+	// Reason is not-a-directory for a stray file, broken-link for a local
+	// registration whose checkout is gone or no longer a repository,
+	// invalid-repo for a directory git cannot open, no-remote for a clone
+	// without an origin, url-mismatch for one whose origin no longer hashes
+	// to its id, and no-head for one whose HEAD resolves to no commit.
+	Reason   string `json:"reason"`
+	Detail   string `json:"detail,omitempty"`
+	Bytes    int64  `json:"bytes"`
+	Modified string `json:"modified"`
+}
+
+// orphanReason says why the entry name under reposDir is a cleanup
+// candidate, or returns "" for a healthy repository. Local registrations
+// are symlinks to a checkout the service does not own, so only their target
+// is checked.
+func orphanReason(name string) (reason, detail string) {
+	path := filepath.Join(reposDir, name)
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "invalid-repo", err.Error()
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		if _, err := openRepository(path); err != nil {
+			return "broken-link", err.Error()
+		}
+		return "", ""
+	}
+	if !info.IsDir() {
+		return "not-a-directory", ""
+	}
+
+	repo, err := openRepository(path)
+	if err != nil {
+		return "invalid-repo", err.Error()
+	}
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if errors.Is(err, git.ErrRemoteNotFound) || err == nil && len(remote.Config().URLs) == 0 {
+		return "no-remote", ""
+	}
+	if err != nil {
+		return "invalid-repo", err.Error()
+	}
+	if url := remote.Config().URLs[0]; repoIDForURL(url) != name {
+		return "url-mismatch", fmt.Sprintf("origin %s belongs under %s", maskRemoteURL(url), repoIDForURL(url))
+	}
+	if _, err := repo.ResolveRevision(plumbing.Revision(plumbing.HEAD)); err != nil {
+		return "no-head", err.Error()
+	}
+	return "", ""
+}
+
+// diskUsage totals the bytes under path, without following symlinks, and
+// finds the latest modification time.
+func diskUsage(path string) (int64, time.Time) {
+	var size int64
+	var modified time.Time
+	filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+		return nil
+	})
+	return size, modified
+}
+
+func orphanedRepo(name, reason, detail string) OrphanedRepo {
+	size, modified := diskUsage(filepath.Join(reposDir, name))
+	return OrphanedRepo{
+		RepoID:   name,
+		Reason:   reason,
+		Detail:   detail,
+		Bytes:    size,
+		Modified: modified.UTC().Format(time.RFC3339),
+	}
+}
+
+// OrphansHandler lists the entries under the repos directory that are not
+// usable repositories, as candidates for cleanup: leftovers of failed
+// clones, stray files and stale local registrations. Clones still in
+// progress are skipped. DELETE removes one of them by its exact id, and
+// refuses anything that is not an orphan.
+func OrphansHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listOrphans(w, r)
+	case http.MethodDelete:
+		deleteOrphan(w, r)
+	default:
+		http.Error(w, "Only GET and DELETE methods are allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func listOrphans(w http.ResponseWriter, r *http.Request) {
+	entries, err := os.ReadDir(reposDir)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read repos directory: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	orphans := []OrphanedRepo{}
+	for _, entry := range entries {
+		if r.Context().Err() != nil {
+			return
+		}
+		name := entry.Name()
+		if runningClones.isRunning(name) {
+			continue
+		}
+		if reason, detail := orphanReason(name); reason != "" {
+			orphans = append(orphans, orphanedRepo(name, reason, detail))
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].RepoID < orphans[j].RepoID
+	})
+
+	writeJSON(w, r, orphans)
+}
+
+func deleteOrphan(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("repoId")
+	if name == "" {
+		http.Error(w, "repoId is required", http.StatusBadRequest)
+		return
+	}
+	if name == "." || name == ".." || filepath.Base(name) != name {
+		http.Error(w, "Invalid repoId", http.StatusBadRequest)
+		return
+	}
+	if _, err := os.Lstat(filepath.Join(reposDir, name)); err != nil {
+		http.Error(w, "Repository not found", http.StatusNotFound)
+		return
+	}
+	if runningClones.isRunning(name) {
+		http.Error(w, "Repository is being cloned; cancel the clone instead", http.StatusConflict)
+		return
+	}
+	reason, detail := orphanReason(name)
+	if reason == "" {
+		http.Error(w, "Repository is not orphaned", http.StatusConflict)
+		return
+	}
+
+	orphan := orphanedRepo(name, reason, detail)
+	// RemoveAll deletes a symlink itself, never the checkout it points to.
+	if err := os.RemoveAll(filepath.Join(reposDir, name)); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to remove repository: %v", err), http.StatusInternalServerError)
+		return
+	}
+	loggerFrom(r.Context()).Info("Orphaned repository removed", "repoId", name, "reason", reason, "bytes", orphan.Bytes)
+	writeJSON(w, r, orphan)
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var (
+	// maxOwnershipPoints caps the snapshots /ownership-trend blames, since
+	// each one blames every file under the path.
+	maxOwnershipPoints = envInt("OWNERSHIP_TREND_MAX_POINTS", 24)
+	// ownershipCacheEntries bounds the cache of per-file blame results.
+	// 0 disables it.
+	ownershipCacheEntries = envInt("OWNERSHIP_CACHE_ENTRIES", 10000)
+)
+
+type OwnershipShare struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	// CanonicalID is the key lines are grouped under, after the mailmap and
+	// IDENTITY_RULES. It is omitted with anonymize.
+	CanonicalID string  `json:"canonicalId,omitempty"`
+	Lines       int     `json:"lines"`
+	Percentage  float64 `json:"percentage"`
+}
+
+type OwnershipSnapshot struct {
+	// Period is the interval the snapshot closes, e.g. 2026-03 for a month.
+	Period     string           `json:"period"`
+	Commit     string           `json:"commit"`
+	Date       string           `json:"date"`
+	TotalLines int              `json:"totalLines"`
+	Authors    []OwnershipShare `json:"authors"`
+	// Truncated is set when the path held more files than
+	// CODE_AGE_MAX_FILES at this point and only the first ones were blamed.
+	Truncated bool `json:"truncated"`
+}
+
+type OwnershipTrendResponse struct {
+	Path      string              `json:"path"`
+	Interval  string              `json:"interval"`
+	Snapshots []OwnershipSnapshot `json:"snapshots"`
+	// Truncated is set when history spans more intervals than were sampled;
+	// only the most recent ones are returned.
+	Truncated bool `json:"truncated"`
+}
+
+// ownershipIntervals maps each interval to the start of the period a time
+// falls in and that period's label.
+var ownershipIntervals = map[string]func(time.Time) (time.Time, string){
+	"day": func(t time.Time) (time.Time, string) {
+		day := utcDay(t)
+		return day, day.Format(time.DateOnly)
+	},
+	"week": func(t time.Time) (time.Time, string) {
+		week := isoWeekStart(t)
+		return week, isoWeekLabel(week)
+	},
+	"month": func(t time.Time) (time.Time, string) {
+		t = t.UTC()
+		month := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return month, month.Format("2006-01")
+	},
+	"year": func(t time.Time) (time.Time, string) {
+		t = t.UTC()
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC), strconv.Itoa(t.Year())
+	},
+}
+
+// blameLines is how many lines of a file each raw author identity owns.
+type blameLines map[identity]int
+
+// fileOwnership caches blame results by repository, commit and path. The
+// blob hash alone is not enough: content that reverts to an earlier version
+// has the same blob, but its lines were last changed by different commits.
+// Snapshots land on the same commits from one request to the next, so a
+// repeated or overlapping trend is not blamed again.
+var fileOwnership = newLRUCache[string, blameLines](ownershipCacheEntries)
+
+func ownershipCacheKey(repoID string, commit plumbing.Hash, path string) string {
+	return repoID + "\x00" + commit.String() + "\x00" + path
+}
+
+// blameOwnership returns how many lines of file each author owns at commit,
+// from the cache when it was blamed there before.
+func blameOwnership(repoID string, commit *object.Commit, file string) (blameLines, error) {
+	key := ownershipCacheKey(repoID, commit.Hash, file)
+	if lines, ok := fileOwnership.get(key); ok {
+		return lines, nil
+	}
+
+	blame, err := git.Blame(commit, file)
+	if err != nil {
+		return nil, err
+	}
+	lines := blameLines{}
+	for _, line := range blame.Lines {
+		lines[identity{Name: line.AuthorName, Email: line.Author}]++
+	}
+	fileOwnership.put(key, lines)
+	return lines, nil
+}
+
+// ownershipPoints walks the first-parent history of tip, newest first, and
+// returns the last commit of each interval period, up to limit of them, with
+// their labels. more reports whether older periods were left out.
+func ownershipPoints(tip *object.Commit, period func(time.Time) (time.Time, string), limit int) (points []*object.Commit, labels []string, more bool, err error) {
+	var current time.Time
+	for c := tip; c != nil; {
+		start, label := period(c.Committer.When)
+		if len(points) == 0 || start.Before(current) {
+			if len(points) == limit {
+				return points, labels, true, nil
+			}
+			current = start
+			points = append(points, c)
+			labels = append(labels, label)
+		}
+		if c.NumParents() == 0 {
+			break
+		}
+		c, err = c.Parent(0)
+		if err != nil {
+			return nil, nil, false, err
+		}
+	}
+	return points, labels, false, nil
+}
+
+// OwnershipTrendHandler blames the files under path at the last commit of
+// each interval on the branch's first-parent history and reports how many of
+// their lines each author owned then, oldest snapshot first. Authors are
+// mailmap-resolved. A path that did not exist yet at a snapshot gives it no
+// lines.
+func OwnershipTrendHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	dir := strings.Trim(query.Get("path"), "/")
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = "month"
+	}
+	period, ok := ownershipIntervals[interval]
+	if !ok {
+		http.Error(w, "interval must be day, week, month or year", http.StatusBadRequest)
+		return
+	}
+	points := maxOwnershipPoints
+	if value := query.Get("points"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "points must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		points = min(n, maxOwnershipPoints)
+	}
+
+	repo, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if len(shallowBoundary(repo)) > 0 {
+		http.Error(w, "Repository is a shallow clone; ownership trend needs its full history", http.StatusConflict)
+		return
+	}
+
+	tip, err := tipCommit(r.Context(), repo, query.Get("branch"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+	commits, labels, more, err := ownershipPoints(tip, period, points)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to walk history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := OwnershipTrendResponse{
+		Path:      dir,
+		Interval:  interval,
+		Snapshots: make([]OwnershipSnapshot, 0, len(commits)),
+		Truncated: more,
+	}
+	authors := newAuthorCounter(loadMailmap(repo))
+	anonymize := parseAnonymize(r)
+	ctx := r.Context()
+	found := false
+	for i := len(commits) - 1; i >= 0; i-- {
+		commit := commits[i]
+		snapshot := OwnershipSnapshot{
+			Period:  labels[i],
+			Commit:  commit.Hash.String(),
+			Date:    commit.Committer.When.Format(time.RFC3339),
+			Authors: []OwnershipShare{},
+		}
+
+		files, err := codeAgeFiles(commit, dir)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read tree: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if len(files) > maxCodeAgeFiles {
+			files = files[:maxCodeAgeFiles]
+			snapshot.Truncated = true
+		}
+		found = found || len(files) > 0
+
+		shares := map[string]*OwnershipShare{}
+		for _, file := range files {
+			if ctx.Err() != nil {
+				return
+			}
+			lines, err := blameOwnership(repoID, commit, file)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to blame %s at %s: %v", file, commit.Hash, err), http.StatusInternalServerError)
+				return
+			}
+			for raw, n := range lines {
+				author, key := authors.resolve(object.Signature{Name: raw.Name, Email: raw.Email})
+				if shares[key] == nil {
+					shares[key] = &OwnershipShare{Name: author.Name, Email: author.Email, CanonicalID: listedCanonicalID(key, anonymize)}
+				}
+				shares[key].Lines += n
+				snapshot.TotalLines += n
+			}
+		}
+
+		for _, share := range shares {
+			if anonymize {
+				share.Name, share.Email = pseudonym(share.Name, share.Email)
+			} else {
+				share.Email = redactEmail(share.Email)
+			}
+			share.Percentage = float64(share.Lines) / float64(snapshot.TotalLines) * 100
+			snapshot.Authors = append(snapshot.Authors, *share)
+		}
+		sort.Slice(snapshot.Authors, func(i, j int) bool {
+			if snapshot.Authors[i].Lines != snapshot.Authors[j].Lines {
+				return snapshot.Authors[i].Lines > snapshot.Authors[j].Lines
+			}
+			return snapshot.Authors[i].Name < snapshot.Authors[j].Name
+		})
+		resp.Snapshots = append(resp.Snapshots, snapshot)
+	}
+	if !found {
+		http.Error(w, "No text files found at path", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, r, resp)
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestBlameOwnershipRevertedContent(t *testing.T) {
+	f := newFixtureRepo(t,
+		fixtureCommit{Message: "Add config\n", Files: map[string]string{"config.txt": "debug=false\n"}},
+		fixtureCommit{Message: "Enable debug\n", Name: "Bob", Email: "bob@example.com", Files: map[string]string{"config.txt": "debug=true\n"}},
+		fixtureCommit{Message: "Revert debug\n", Name: "Carol", Email: "carol@example.com", Files: map[string]string{"config.txt": "debug=false\n"}},
+	)
+	previous := fileOwnership
+	fileOwnership = newLRUCache[string, blameLines](ownershipCacheEntries)
+	t.Cleanup(func() { fileOwnership = previous })
+
+	want := []identity{
+		{Name: "Alice", Email: "alice@example.com"},
+		{Name: "Bob", Email: "bob@example.com"},
+		{Name: "Carol", Email: "carol@example.com"},
+	}
+	// Oldest first, so the reverted blob is cached from the first commit
+	// before the last one is blamed.
+	for i, hash := range f.Hashes {
+		commit, err := f.Repo.CommitObject(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines, err := blameOwnership(f.ID, commit, "config.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 1 || lines[want[i]] != 1 {
+			t.Errorf("commit %d: blame = %v, want %v owning the line", i, lines, want[i])
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const totalCountHeader = "X-Total-Count"
+
+// pageLink builds one entry of a Link header pointing at this request with
+// the given query parameters replaced. An empty value removes the parameter.
+func pageLink(r *http.Request, rel string, params map[string]string) string {
+	u := *r.URL
+	query := u.Query()
+	for name, value := range params {
+		if value == "" {
+			query.Del(name)
+		} else {
+			query.Set(name, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return "<" + u.RequestURI() + `>; rel="` + rel + `"`
+}
+
+// setPaginationHeaders reports the total number of items across all pages and
+// links to the neighbouring pages, so generic clients can page without
+// reading the body.
+func setPaginationHeaders(w http.ResponseWriter, total int, links []string) {
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// parseOffset reads the offset of an offset-paginated list.
+func parseOffset(r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("offset")
+	if value == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(value)
+	return n, err == nil && n >= 0
+}
+
+// offsetPage cuts the page at offset and limit, with limit 0 meaning the rest
+// of the list, and returns its bounds and the Link entries next to it.
+func offsetPage(r *http.Request, total, offset, limit int) (start, end int, links []string) {
+	start = min(offset, total)
+	end = total
+	if limit > 0 {
+		end = min(start+limit, total)
+		if end < total {
+			links = append(links, pageLink(r, "next", map[string]string{"offset": strconv.Itoa(end)}))
+		}
+		if start > 0 {
+			links = append(links, pageLink(r, "prev", map[string]string{"offset": strconv.Itoa(max(0, start-limit))}))
+		}
+	}
+	return start, end, links
+}
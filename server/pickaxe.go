@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+var (
+	// maxPickaxeCommits caps the commits /pickaxe looks at, since each one
+	// is diffed and its changed files read.
+	maxPickaxeCommits = envInt("PICKAXE_MAX_COMMITS", 5000)
+	// maxPickaxeFileBytes skips files too large to search.
+	maxPickaxeFileBytes = int64(envInt("PICKAXE_MAX_FILE_BYTES", 1<<20))
+)
+
+type PickaxeFile struct {
+	Path string `json:"path"`
+	// Before and After count the occurrences of the query in the file before
+	// and after the commit. They are omitted for regex searches, which look
+	// at the changed lines instead.
+	Before *int `json:"before,omitempty"`
+	After  *int `json:"after,omitempty"`
+}
+
+type PickaxeCommit struct {
+	Commit
+	Files []PickaxeFile `json:"files"`
+}
+
+type PickaxeResponse struct {
+	Query   string          `json:"query"`
+	Regex   bool            `json:"regex"`
+	Scanned int             `json:"scanned"`
+	Commits []PickaxeCommit `json:"commits"`
+	// Truncated is set when the walk stopped at PICKAXE_MAX_COMMITS before
+	// reaching the start of history.
+	Truncated bool `json:"truncated"`
+}
+
+// pickaxeSearch decides whether a change to one file matches.
+type pickaxeSearch struct {
+	literal string
+	pattern *regexp.Regexp
+}
+
+// match implements git log -S for a literal query: the number of
+// occurrences differs between the two versions of the file. For a regex it
+// implements -G: an added or removed line matches.
+func (p pickaxeSearch) match(ctx context.Context, change *object.Change) (PickaxeFile, bool, error) {
+	file := PickaxeFile{Path: change.To.Name}
+	if file.Path == "" {
+		file.Path = change.From.Name
+	}
+	from, to, err := change.Files()
+	if err != nil {
+		return file, false, err
+	}
+	for _, f := range []*object.File{from, to} {
+		if f == nil {
+			continue
+		}
+		if f.Size > maxPickaxeFileBytes {
+			return file, false, nil
+		}
+		if binary, err := f.IsBinary(); err != nil || binary {
+			return file, false, err
+		}
+	}
+
+	if p.pattern != nil {
+		patch, err := change.PatchContext(ctx)
+		if err != nil {
+			return file, false, err
+		}
+		for _, filePatch := range patch.FilePatches() {
+			for _, chunk := range filePatch.Chunks() {
+				if chunk.Type() == diff.Equal {
+					continue
+				}
+				for _, line := range strings.Split(chunk.Content(), "\n") {
+					if p.pattern.MatchString(line) {
+						return file, true, nil
+					}
+				}
+			}
+		}
+		return file, false, nil
+	}
+
+	before, err := p.count(from)
+	if err != nil {
+		return file, false, err
+	}
+	after, err := p.count(to)
+	if err != nil {
+		return file, false, err
+	}
+	file.Before, file.After = &before, &after
+	return file, before != after, nil
+}
+
+func (p pickaxeSearch) count(f *object.File) (int, error) {
+	if f == nil {
+		return 0, nil
+	}
+	contents, err := f.Contents()
+	if err != nil {
+		return 0, err
+	}
+	return strings.Count(contents, p.literal), nil
+}
+
+// PickaxeHandler finds the commits that changed how often a string occurs
+// in the files under path, like git log -S, or with regex=true the commits
+// whose added or removed lines match a regular expression, like git log -G.
+// It answers "when was this function added or removed". Merge commits are
+// skipped, as git does by default, and at most PICKAXE_MAX_COMMITS commits
+// are scanned, newest first.
+func PickaxeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	q := query.Get("q")
+	if q == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	search := pickaxeSearch{literal: q}
+	resp := PickaxeResponse{Query: q, Regex: query.Get("regex") == "true", Commits: []PickaxeCommit{}}
+	if resp.Regex {
+		pattern, err := regexp.Compile(q)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid regex: %v", err), http.StatusBadRequest)
+			return
+		}
+		search.pattern = pattern
+	}
+
+	commitOpts, err := parseCommitOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+	// Paths are checked against the changes diffed below, so the filter
+	// does not need to diff every commit a second time.
+	commitFilter := filter
+	commitFilter.Paths = nil
+
+	iter, err := commitLog(r.Context(), repo, query.Get("branch"), filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	ctx := r.Context()
+	err = iter.ForEach(func(c *object.Commit) error {
+		if resp.Scanned == maxPickaxeCommits {
+			resp.Truncated = true
+			return storer.ErrStop
+		}
+		resp.Scanned++
+		if c.NumParents() > 1 || !commitFilter.matches(c) {
+			return nil
+		}
+		changes, err := commitChanges(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		var files []PickaxeFile
+		for _, change := range changes {
+			if !filter.includesFile(change.From.Name) && !filter.includesFile(change.To.Name) {
+				continue
+			}
+			file, matched, err := search.match(ctx, change)
+			if err != nil {
+				return err
+			}
+			if matched {
+				files = append(files, file)
+			}
+		}
+		if len(files) > 0 {
+			resp.Commits = append(resp.Commits, PickaxeCommit{Commit: newCommit(c, commitOpts), Files: files})
+		}
+		return nil
+	})
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, resp)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+const (
+	defaultPollTimeout = 30 * time.Second
+	maxPollTimeout     = 120 * time.Second
+	// pollRecheckInterval catches updates nothing notifies about, such as
+	// commits made directly in a registered local checkout.
+	pollRecheckInterval = 2 * time.Second
+)
+
+// updateNotifier lets requests wait for a repository to change. Waiters take
+// the current channel for a repo and the next notify closes it, waking all of
+// them at once.
+type updateNotifier struct {
+	mu       sync.Mutex
+	channels map[string]chan struct{}
+}
+
+var repoUpdates = &updateNotifier{channels: map[string]chan struct{}{}}
+
+func (n *updateNotifier) subscribe(repoID string) <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ch, ok := n.channels[repoID]
+	if !ok {
+		ch = make(chan struct{})
+		n.channels[repoID] = ch
+	}
+	return ch
+}
+
+// notify wakes every request waiting on repoID. Anything that changes refs
+// on disk (clone, fetch, webhook-triggered pull) should call it.
+func (n *updateNotifier) notify(repoID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if ch, ok := n.channels[repoID]; ok {
+		close(ch)
+		delete(n.channels, repoID)
+	}
+}
+
+type PollResponse struct {
+	Head    string   `json:"head"`
+	Commits []Commit `json:"commits"`
+}
+
+// PollHandler is a long-poll alternative to the SSE streams. It returns the
+// commits on branch (HEAD by default) newer than after right away when there
+// are any, otherwise it waits up to timeout seconds for new commits to appear
+// and returns an empty list if none do.
+func PollHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	after := query.Get("after")
+	if after == "" {
+		http.Error(w, "after is required", http.StatusBadRequest)
+		return
+	}
+
+	timeout := defaultPollTimeout
+	if value := query.Get("timeout"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "timeout must be a non-negative number of seconds", http.StatusBadRequest)
+			return
+		}
+		timeout = min(time.Duration(n)*time.Second, maxPollTimeout)
+	}
+
+	commitOpts, err := parseCommitOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Subscribe before the first look so an update landing in between is
+	// not missed.
+	updated := repoUpdates.subscribe(repoID)
+	resp, err := commitsAfter(r.Context(), repoID, query.Get("branch"), after, commitOpts)
+	if err == nil && len(resp.Commits) == 0 {
+		// The wait may outlast the server timeouts.
+		clearDeadlines(w)
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		recheck := time.NewTicker(pollRecheckInterval)
+		defer recheck.Stop()
+	wait:
+		for {
+			select {
+			case <-updated:
+				updated = repoUpdates.subscribe(repoID)
+			case <-recheck.C:
+			case <-timer.C:
+				break wait
+			case <-r.Context().Done():
+				return
+			}
+			resp, err = commitsAfter(r.Context(), repoID, query.Get("branch"), after, commitOpts)
+			if err != nil || len(resp.Commits) > 0 {
+				break wait
+			}
+		}
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, r, resp)
+}
+
+// commitsAfter reopens the repository, so refs updated on disk are seen, and
+// collects the commits on branch newer than after, newest first. after must
+// be in the branch's history.
+func commitsAfter(ctx context.Context, repoID, branch, after string, opts commitOptions) (PollResponse, error) {
+	repo, _, err := getRepo(repoID)
+	if err != nil {
+		return PollResponse{}, err
+	}
+	iter, err := commitLog(ctx, repo, branch, nil)
+	if err != nil {
+		return PollResponse{}, err
+	}
+
+	resp := PollResponse{Commits: []Commit{}}
+	found := false
+	err = iter.ForEach(func(c *object.Commit) error {
+		if resp.Head == "" {
+			resp.Head = c.Hash.String()
+		}
+		if c.Hash.String() == after {
+			found = true
+			return storer.ErrStop
+		}
+		resp.Commits = append(resp.Commits, newCommit(c, opts))
+		return nil
+	})
+	if err != nil {
+		return PollResponse{}, err
+	}
+	if !found {
+		return PollResponse{}, errCommitNotFound
+	}
+	return resp, nil
+}
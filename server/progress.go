@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CloneProgress is one structured update parsed from the sideband progress
+// lines a remote sends during a clone, e.g.
+// "Receiving objects:  45% (450/1000), 1.20 MiB | 500.00 KiB/s".
+type CloneProgress struct {
+	Phase         string `json:"phase"`
+	Current       int    `json:"current"`
+	Total         int    `json:"total,omitempty"`
+	Percent       int    `json:"percent,omitempty"`
+	Done          bool   `json:"done,omitempty"`
+	BytesReceived int64  `json:"bytesReceived,omitempty"`
+}
+
+var progressPhases = map[string]string{
+	"enumerating objects": "enumerating",
+	"counting objects":    "counting",
+	"compressing objects": "compressing",
+	"receiving objects":   "receiving",
+	"resolving deltas":    "resolving",
+	"checking out files":  "checking-out",
+	"updating files":      "checking-out",
+}
+
+var (
+	progressLineRe  = regexp.MustCompile(`^([A-Za-z ]+):\s+(?:(\d+)%\s+\((\d+)/(\d+)\)|(\d+))`)
+	progressBytesRe = regexp.MustCompile(`([\d.]+) (B|KiB|MiB|GiB)\b`)
+)
+
+var byteUnits = map[string]float64{"B": 1, "KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30}
+
+func parseProgressLine(line string) (CloneProgress, bool) {
+	m := progressLineRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return CloneProgress{}, false
+	}
+	phase, ok := progressPhases[strings.ToLower(strings.TrimSpace(m[1]))]
+	if !ok {
+		return CloneProgress{}, false
+	}
+
+	p := CloneProgress{Phase: phase, Done: strings.HasSuffix(strings.TrimSpace(line), "done.")}
+	if m[2] != "" {
+		p.Percent, _ = strconv.Atoi(m[2])
+		p.Current, _ = strconv.Atoi(m[3])
+		p.Total, _ = strconv.Atoi(m[4])
+	} else {
+		p.Current, _ = strconv.Atoi(m[5])
+	}
+	if b := progressBytesRe.FindStringSubmatch(line); b != nil {
+		n, _ := strconv.ParseFloat(b[1], 64)
+		p.BytesReceived = int64(n * byteUnits[b[2]])
+	}
+	return p, true
+}
+
+// progressWriter is handed to go-git as the clone Progress writer. It splits
+// the stream on the \r and \n that git uses to redraw its progress lines and
+// reports each parsed update that differs from the previous one, so callers
+// are not flooded with identical percentages.
+type progressWriter struct {
+	onProgress func(CloneProgress)
+	buf        []byte
+	last       CloneProgress
+}
+
+func newProgressWriter(onProgress func(CloneProgress)) *progressWriter {
+	return &progressWriter{onProgress: onProgress}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		line := string(w.buf[:i])
+		w.buf = w.buf[i+1:]
+
+		progress, ok := parseProgressLine(line)
+		if !ok || progress == w.last {
+			continue
+		}
+		if progress.Phase == w.last.Phase && progress.Percent == w.last.Percent && !progress.Done {
+			continue
+		}
+		w.last = progress
+		w.onProgress(progress)
+	}
+	return len(p), nil
+}
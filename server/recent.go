@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+const recentTopFiles = 3
+
+type RecentActivity struct {
+	Days          int          `json:"days"`
+	Since         string       `json:"since"`
+	Commits       int          `json:"commits"`
+	ActiveAuthors int          `json:"activeAuthors"`
+	Additions     int          `json:"additions"`
+	Deletions     int          `json:"deletions"`
+	NetLines      int          `json:"netLines"`
+	TopFiles      []*FileChurn `json:"topFiles"`
+}
+
+// RecentHandler summarises the last days of activity. The walk is in
+// committer-date order and stops at the first commit committed before the
+// window, so only the recent part of history is read.
+func RecentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days, err := parseDays(r, 7)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	since := time.Now().AddDate(0, 0, -days)
+	opts := filter.logOptions()
+	opts.Order = git.LogOrderCommitterTime
+	iter, err := commitLog(r.Context(), repo, r.URL.Query().Get("branch"), opts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	resp := RecentActivity{Days: days, Since: since.Format(time.RFC3339)}
+	authors := newAuthorCounter(loadMailmap(repo))
+	files := churnCounter{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if c.Committer.When.Before(since) {
+			return storer.ErrStop
+		}
+		if !filter.matches(c) || c.Author.When.Before(since) {
+			return nil
+		}
+
+		resp.Commits++
+		authors.add(c.Author)
+
+		modifications, err := commitModifications(c)
+		if err != nil {
+			return err
+		}
+		for _, mod := range modifications {
+			if !filter.includesFile(mod.File) {
+				continue
+			}
+			resp.Additions += mod.Additions
+			resp.Deletions += mod.Deletions
+			files.add(mod, c.Author.When)
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp.ActiveAuthors = authors.len()
+	resp.NetLines = resp.Additions - resp.Deletions
+	resp.TopFiles = files.sorted(fileChurnSorts["churn"], recentTopFiles)
+
+	writeJSON(w, r, resp)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const redactedText = "***"
+
+// redactPatterns are the regular expressions from REDACT_PATTERNS, one per
+// line so patterns can contain commas. Redaction is off when it is unset.
+// main compiles them at startup.
+var redactPatterns []*regexp.Regexp
+
+// redactEmails extends redaction to author emails.
+var redactEmails = os.Getenv("REDACT_EMAILS") == "true"
+
+// compileRedactPatterns rejects an invalid pattern so a misconfigured
+// deployment fails at startup instead of silently leaking what it meant to
+// hide.
+func compileRedactPatterns(value string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range strings.Split(value, "\n") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDACT_PATTERNS entry %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// redactText replaces every match of the configured patterns with ***.
+func redactText(text string) string {
+	for _, re := range redactPatterns {
+		text = re.ReplaceAllString(text, redactedText)
+	}
+	return text
+}
+
+func redactEmail(email string) string {
+	if !redactEmails {
+		return email
+	}
+	return redactText(email)
+}
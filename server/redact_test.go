@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileRedactPatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+		text    string
+		want    string
+	}{
+		{name: "unset", value: "", text: "token sk-abc123", want: "token sk-abc123"},
+		{name: "one per line", value: "sk-[a-z0-9]+\n\n  ghp_\\w+  \n", text: "sk-abc123 and ghp_XYZ", want: "*** and ***"},
+		{name: "commas kept in a pattern", value: "a{1,2}b", text: "aab", want: "***"},
+		{name: "invalid pattern", value: "sk-[a-z", wantErr: `invalid REDACT_PATTERNS entry "sk-[a-z"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patterns, err := compileRedactPatterns(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			previous := redactPatterns
+			redactPatterns = patterns
+			defer func() { redactPatterns = previous }()
+			if got := redactText(tt.text); got != tt.want {
+				t.Errorf("redactText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type ReleaseWindow struct {
+	// Tag is the release the window ends at; it is empty for the unreleased
+	// window after the last tag.
+	Tag         string `json:"tag,omitempty"`
+	PreviousTag string `json:"previousTag,omitempty"`
+	Hash        string `json:"hash"`
+	Date        string `json:"date"`
+	Unreleased  bool   `json:"unreleased"`
+	Commits     int    `json:"commits"`
+	// Contributors counts distinct mailmap-resolved authors.
+	Contributors int `json:"contributors"`
+	Additions    int `json:"additions"`
+	Deletions    int `json:"deletions"`
+	NetLines     int `json:"netLines"`
+	// TopByCommits and TopByLines credit the release's biggest human
+	// contributors, at most top of each. Bots are left out of both.
+	TopByCommits []ReleaseContributor `json:"topByCommits"`
+	TopByLines   []ReleaseContributor `json:"topByLines"`
+}
+
+type ReleaseContributor struct {
+	ContributorCount
+	// Lines is the additions plus deletions in the author's non-merge
+	// commits.
+	Lines int `json:"lines"`
+}
+
+const defaultReleaseTop = 5
+
+// topReleaseContributors ranks credited authors by commits and by lines,
+// breaking ties on the other measure and then by name.
+func topReleaseContributors(credited *authorCounter, lines map[string]int, top int) (byCommits, byLines []ReleaseContributor) {
+	all := make([]ReleaseContributor, 0, credited.len())
+	for key, count := range credited.counts {
+		all = append(all, ReleaseContributor{ContributorCount: credited.listed(count), Lines: lines[key]})
+	}
+	ranked := func(primary, secondary func(ReleaseContributor) int) []ReleaseContributor {
+		list := append([]ReleaseContributor(nil), all...)
+		sort.Slice(list, func(i, j int) bool {
+			if primary(list[i]) != primary(list[j]) {
+				return primary(list[i]) > primary(list[j])
+			}
+			if secondary(list[i]) != secondary(list[j]) {
+				return secondary(list[i]) > secondary(list[j])
+			}
+			return list[i].Name < list[j].Name
+		})
+		return list[:min(top, len(list))]
+	}
+	commits := func(c ReleaseContributor) int { return c.Commits }
+	changed := func(c ReleaseContributor) int { return c.Lines }
+	return ranked(commits, changed), ranked(changed, commits)
+}
+
+// releaseWindows splits history into one window per tag, oldest first, plus
+// a final window for what tip adds after the last tag. Each window holds the
+// commits reachable from its tag that no earlier window already took, so the
+// first tag's window starts at the root commits and every commit is counted
+// once, in the first release that contains it. Each walk stops at commits
+// already assigned, which keeps the whole split at one pass over history.
+func releaseWindows(repo *git.Repository, tags []taggedCommit, tip *object.Commit) ([][]*object.Commit, error) {
+	assigned := map[plumbing.Hash]bool{}
+	windows := make([][]*object.Commit, 0, len(tags)+1)
+	for _, tag := range append(tags, taggedCommit{Commit: tip}) {
+		window, err := unassignedAncestors(repo, tag.Commit, assigned)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// unassignedAncestors returns from and its ancestors that are not in
+// assigned, and adds them to it. Parents missing from a shallow clone are
+// skipped.
+func unassignedAncestors(repo *git.Repository, from *object.Commit, assigned map[plumbing.Hash]bool) ([]*object.Commit, error) {
+	var window []*object.Commit
+	stack := []*object.Commit{from}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if assigned[c.Hash] {
+			continue
+		}
+		assigned[c.Hash] = true
+		window = append(window, c)
+		for _, hash := range c.ParentHashes {
+			if assigned[hash] {
+				continue
+			}
+			parent, err := repo.CommitObject(hash)
+			if errors.Is(err, plumbing.ErrObjectNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, parent)
+		}
+	}
+	return window, nil
+}
+
+// ReleasesHandler treats tags as releases and reports, for each one, the
+// commits, contributors and lines changed since the previous tag by commit
+// date. The first window reaches back to the start of history and a last,
+// unreleased window covers the branch tip after the final tag. Merge commits
+// count as commits but not towards the line totals, since their diff repeats
+// the merged commits. Each window also names its top contributors, top of
+// them (5 by default) by commits and by lines, for crediting in release
+// notes.
+func ReleasesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	top := defaultReleaseTop
+	if value := r.URL.Query().Get("top"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "top must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	tags, err := listTags(repo)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get tags: %v", err), http.StatusInternalServerError)
+		return
+	}
+	tip, err := tipCommit(r.Context(), repo, r.URL.Query().Get("branch"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	windows, err := releaseWindows(repo, tags, tip)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to walk tag history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	mm := loadMailmap(repo)
+	anonymize := parseAnonymize(r)
+	releases := make([]ReleaseWindow, 0, len(windows))
+	for i, window := range windows {
+		release := ReleaseWindow{}
+		authors := newAuthorCounter(mm)
+		credited := newAuthorCounter(mm)
+		credited.anonymize = anonymize
+		lines := map[string]int{}
+		if i < len(tags) {
+			release.Tag = tags[i].Name
+			release.Hash = tags[i].Commit.Hash.String()
+			release.Date = tags[i].Commit.Committer.When.Format(time.RFC3339)
+		} else {
+			release.Unreleased = true
+			release.Hash = tip.Hash.String()
+			release.Date = tip.Committer.When.Format(time.RFC3339)
+		}
+		if i > 0 {
+			release.PreviousTag = tags[i-1].Name
+		}
+
+		for _, c := range window {
+			if !filter.matches(c) {
+				continue
+			}
+			release.Commits++
+			authors.add(c.Author)
+			bot := filter.isBot(c.Author)
+			if !bot {
+				credited.add(c.Author)
+			}
+			if c.NumParents() > 1 {
+				continue
+			}
+			_, key := credited.resolve(c.Author)
+			modifications, err := commitModifications(c)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+				return
+			}
+			for _, mod := range modifications {
+				if filter.includesFile(mod.File) {
+					release.Additions += mod.Additions
+					release.Deletions += mod.Deletions
+					if !bot {
+						lines[key] += mod.Additions + mod.Deletions
+					}
+				}
+			}
+		}
+		release.Contributors = authors.len()
+		release.NetLines = release.Additions - release.Deletions
+		release.TopByCommits, release.TopByLines = topReleaseContributors(credited, lines, top)
+		releases = append(releases, release)
+	}
+
+	writeJSON(w, r, releases)
+}
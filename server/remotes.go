@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+type RemoteInfo struct {
+	Name string   `json:"name"`
+	URLs []string `json:"urls"`
+}
+
+const maskedCredential = "redacted"
+
+// maskRemoteURL hides credentials embedded in a remote URL. Passwords are
+// always masked; a bare HTTP(S) username is masked too since it is usually a
+// token. scp-style SSH addresses (git@host:path) do not parse as URLs and
+// carry no secret, so they are returned unchanged.
+func maskRemoteURL(remoteURL string) string {
+	u, err := url.Parse(remoteURL)
+	if err != nil || u.User == nil {
+		return remoteURL
+	}
+
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), maskedCredential)
+	} else if u.Scheme == "http" || u.Scheme == "https" {
+		u.User = url.User(maskedCredential)
+	}
+	return u.String()
+}
+
+func RemotesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	remotes, err := repo.Remotes()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get remotes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	infos := make([]RemoteInfo, 0, len(remotes))
+	for _, remote := range remotes {
+		config := remote.Config()
+		info := RemoteInfo{Name: config.Name, URLs: make([]string, 0, len(config.URLs))}
+		for _, remoteURL := range config.URLs {
+			info.URLs = append(info.URLs, maskRemoteURL(remoteURL))
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	writeJSON(w, r, infos)
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// reposDir and openRepository are variables so the storage location and the
+// way repositories are opened can be swapped out, e.g. for a temp directory.
+var (
+	reposDir       = envString("REPOS_DIR", "repos")
+	openRepository = git.PlainOpen
+)
+
+var (
+	errInvalidRepoID   = errors.New("invalid repository id")
+	errAmbiguousRepoID = errors.New("ambiguous repository id")
+)
+
+// normalizeRepoURL drops surrounding space and a trailing "/" or ".git", and
+// lowercases the scheme and host, which are case-insensitive. The path keeps
+// its case: servers can treat Owner/Repo and owner/repo as different
+// repositories.
+func normalizeRepoURL(repoURL string) string {
+	normalized := strings.TrimSpace(repoURL)
+	normalized = strings.TrimRight(normalized, "/")
+	normalized = strings.TrimSuffix(normalized, ".git")
+	normalized = strings.TrimRight(normalized, "/")
+
+	scheme, rest, ok := strings.Cut(normalized, "://")
+	if !ok {
+		// scp-like syntax (user@host:path) has a host only when the colon
+		// comes before any slash; anything else is a local path.
+		colon := strings.IndexByte(normalized, ':')
+		if colon <= 0 || strings.ContainsAny(normalized[:colon], `/\`) {
+			return normalized
+		}
+		return lowerHost(normalized[:colon]) + normalized[colon:]
+	}
+	authority, path := rest, ""
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		authority, path = rest[:i], rest[i:]
+	}
+	return strings.ToLower(scheme) + "://" + lowerHost(authority) + path
+}
+
+// lowerHost lowercases the host of an authority, leaving any user name as
+// it is.
+func lowerHost(authority string) string {
+	i := strings.LastIndexByte(authority, '@') + 1
+	return authority[:i] + strings.ToLower(authority[i:])
+}
+
+// repoIDForURL derives the storage id for a repository URL: the repository
+// name for readability, plus a short hash of the full URL so that two repos
+// with the same name from different owners never share a directory.
+func repoIDForURL(repoURL string) string {
+	normalized := normalizeRepoURL(repoURL)
+
+	name := normalized
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" || name == "." || name == ".." {
+		name = "repo"
+	}
+
+	sum := sha256.Sum256([]byte(normalized))
+	return name + "-" + hex.EncodeToString(sum[:4])
+}
+
+// resolveRepoID maps an id to its directory under reposDir. Besides the full
+// id, the bare repository name is accepted as long as exactly one stored
+// repository has that name.
+func resolveRepoID(repoID string) (string, error) {
+	if repoID == "" || repoID == "." || repoID == ".." || strings.ContainsAny(repoID, `/\`) {
+		return "", errInvalidRepoID
+	}
+
+	if _, err := os.Stat(filepath.Join(reposDir, repoID)); err == nil {
+		return repoID, nil
+	}
+
+	entries, err := os.ReadDir(reposDir)
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, repoID+"-") && len(name) == len(repoID)+9 {
+			matches = append(matches, name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", git.ErrRepositoryNotExists
+	case 1:
+		return matches[0], nil
+	default:
+		return "", errAmbiguousRepoID
+	}
+}
+
+func getRepo(repoID string) (*git.Repository, string, error) {
+	resolved, err := resolveRepoID(repoID)
+	if err != nil {
+		return nil, "", err
+	}
+	repo, err := openRepository(filepath.Join(reposDir, resolved))
+	return repo, resolved, err
+}
+
+func repoFromRequest(w http.ResponseWriter, r *http.Request) (*git.Repository, string, bool) {
+	return openRequestedRepo(w, r.URL.Query().Get("repoId"))
+}
+
+// openRequestedRepo opens the repository for a client-supplied repoId,
+// writing the matching error response and returning false when it cannot.
+func openRequestedRepo(w http.ResponseWriter, repoID string) (*git.Repository, string, bool) {
+	if repoID == "" {
+		http.Error(w, "repoId is required", http.StatusBadRequest)
+		return nil, "", false
+	}
+
+	repo, resolved, err := getRepo(repoID)
+	if err != nil {
+		http.Error(w, repoErrorMessage(err), repoErrorStatus(err))
+		return nil, "", false
+	}
+	setShallowHeader(w, repo)
+	return repo, resolved, true
+}
+
+// repoErrorMessage and repoErrorStatus describe a getRepo failure to the
+// client.
+func repoErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, errInvalidRepoID):
+		return "Invalid repoId"
+	case errors.Is(err, errAmbiguousRepoID):
+		return "repoId matches more than one repository, use the full id"
+	}
+	return "Repository not found"
+}
+
+func repoErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errInvalidRepoID):
+		return http.StatusBadRequest
+	case errors.Is(err, errAmbiguousRepoID):
+		return http.StatusConflict
+	}
+	return http.StatusNotFound
+}
+
+var errBranchNotFound = errors.New("branch not found")
+
+func resolveBranch(repo *git.Repository, branch string) (plumbing.Hash, error) {
+	candidates := []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(branch),
+		plumbing.NewRemoteReferenceName("origin", branch),
+		plumbing.ReferenceName("refs/remotes/" + branch),
+	}
+	for _, name := range candidates {
+		ref, err := repo.Reference(name, true)
+		if err == nil {
+			return ref.Hash(), nil
+		}
+	}
+	return plumbing.ZeroHash, errBranchNotFound
+}
+
+// commitLog walks history from the tip of branch, or from HEAD when branch
+// is empty and the repository config sets no default branch. A branch with
+// a relative suffix (main~2, HEAD^) starts the walk at the commit it
+// resolves to.
+func commitLog(ctx context.Context, repo *git.Repository, branch string, opts *git.LogOptions) (object.CommitIter, error) {
+	if opts == nil {
+		opts = &git.LogOptions{}
+	}
+	if branch == "" {
+		branch = repoConfigFor(ctx, repo).DefaultBranch
+	}
+
+	if branch == "" {
+		ref, err := repo.Head()
+		if err != nil {
+			return nil, err
+		}
+		opts.From = ref.Hash()
+	} else if strings.ContainsAny(branch, "~^") {
+		commit, err := resolveCommit(repo, branch)
+		if err != nil {
+			return nil, err
+		}
+		opts.From = commit.Hash
+	} else {
+		hash, err := resolveBranch(repo, branch)
+		if err != nil {
+			return nil, err
+		}
+		opts.From = hash
+	}
+	return repoLog(repo, opts)
+}
+
+// tipCommit returns the commit a walk of branch would start from, with the
+// same defaults as commitLog.
+func tipCommit(ctx context.Context, repo *git.Repository, branch string) (*object.Commit, error) {
+	iter, err := commitLog(ctx, repo, branch, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	return iter.Next()
+}
+
+func logErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errBranchNotFound), errors.Is(err, errCommitNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errInvalidRevision):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		loggerFrom(r.Context()).Error("Error encoding JSON response", "error", err)
+	}
+}
+
+// headState reports the branch HEAD points at, or detached=true when HEAD
+// names a commit directly (e.g. after checking out a tag). History walks
+// default to the HEAD commit either way.
+func headState(repo *git.Repository) (branch string, detached bool, err error) {
+	ref, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", false, err
+	}
+	if ref.Type() == plumbing.HashReference {
+		return "", true, nil
+	}
+	return ref.Target().Short(), false, nil
+}
+
+var errCommitNotFound = errors.New("commit not found")
+
+// resolveCommit resolves a full or abbreviated hash, branch or tag name to a
+// commit, following any relative suffix such as ~3 or ^2. It returns
+// errInvalidRevision for malformed expressions and errCommitNotFound when
+// the base or one of the requested parents does not exist.
+func resolveCommit(repo *git.Repository, rev string) (*object.Commit, error) {
+	base, steps, err := parseRevision(rev)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(base))
+	if err != nil {
+		return nil, errCommitNotFound
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, errCommitNotFound
+	}
+
+	for _, step := range steps {
+		switch {
+		case step.ancestor:
+			for i := 0; i < step.n; i++ {
+				if commit, err = commit.Parent(0); err != nil {
+					return nil, errCommitNotFound
+				}
+			}
+		case step.n > 0:
+			if commit, err = commit.Parent(step.n - 1); err != nil {
+				return nil, errCommitNotFound
+			}
+		}
+	}
+	return commit, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestRepoIDForURL(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{name: "host case", a: "https://GitHub.com/acme/App.git", b: "https://github.com/acme/App", same: true},
+		{name: "scheme case", a: "HTTPS://github.com/acme/App", b: "https://github.com/acme/App", same: true},
+		{name: "trailing slash", a: "https://github.com/acme/App/", b: "https://github.com/acme/App.git", same: true},
+		{name: "path case", a: "https://github.com/acme/App", b: "https://github.com/acme/app"},
+		{name: "owner case", a: "https://github.com/Acme/app", b: "https://github.com/acme/app"},
+		{name: "scp host case", a: "git@GitHub.com:acme/App.git", b: "git@github.com:acme/App", same: true},
+		{name: "scp path case", a: "git@github.com:acme/App.git", b: "git@github.com:acme/app.git"},
+		{name: "user name case", a: "ssh://Deploy@github.com/acme/app", b: "ssh://deploy@github.com/acme/app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := repoIDForURL(tt.a), repoIDForURL(tt.b)
+			if (a == b) != tt.same {
+				t.Errorf("repoIDForURL(%q) = %s, repoIDForURL(%q) = %s, want same = %t", tt.a, a, tt.b, b, tt.same)
+			}
+		})
+	}
+}
+
+func TestNormalizeRepoURL(t *testing.T) {
+	tests := map[string]string{
+		"  https://GitHub.com/Acme/App.git/ ":    "https://github.com/Acme/App",
+		"ssh://Deploy@Example.COM:2222/Acme/App": "ssh://Deploy@example.com:2222/Acme/App",
+		"git@GitHub.com:Acme/App.git":            "git@github.com:Acme/App",
+		"/srv/CI/App.git":                        "/srv/CI/App",
+		"./Repos/App:v2":                         "./Repos/App:v2",
+	}
+	for in, want := range tests {
+		if got := normalizeRepoURL(in); got != want {
+			t.Errorf("normalizeRepoURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// repoConfigFile is read from the top of the repository directory: the
+// working tree of a normal clone or the repository itself for a bare one.
+const repoConfigFile = "insights.json"
+
+// RepoConfig holds per-repository defaults. Query parameters still win:
+// branch= overrides DefaultBranch and ignore= replaces IgnorePaths.
+type RepoConfig struct {
+	// DefaultBranch is walked when a request names no branch, instead of
+	// HEAD.
+	DefaultBranch string `json:"defaultBranch,omitempty"`
+	// IgnorePaths are globs dropped from per-file results. A pattern matches
+	// the full path, the base name, or a directory prefix.
+	IgnorePaths []string `json:"ignorePaths,omitempty"`
+	// BotPatterns replace BOT_PATTERNS for excludeBots on this repository.
+	BotPatterns []string `json:"botPatterns,omitempty"`
+}
+
+func (c RepoConfig) validate() error {
+	if c.DefaultBranch != "" && strings.TrimSpace(c.DefaultBranch) != c.DefaultBranch {
+		return fmt.Errorf("defaultBranch must not have surrounding spaces")
+	}
+	for _, pattern := range c.IgnorePaths {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("invalid ignorePaths pattern %q", pattern)
+		}
+	}
+	for _, pattern := range c.BotPatterns {
+		if strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("botPatterns must not contain empty patterns")
+		}
+	}
+	return nil
+}
+
+func repoConfigPath(repo *git.Repository) (string, error) {
+	if wt, err := repo.Worktree(); err == nil {
+		return filepath.Join(wt.Filesystem.Root(), repoConfigFile), nil
+	}
+	if storage, ok := repo.Storer.(*filesystem.Storage); ok {
+		return filepath.Join(storage.Filesystem().Root(), repoConfigFile), nil
+	}
+	return "", errors.New("repository has no directory for a config file")
+}
+
+// loadRepoConfig reads the repository's config file. A missing file is the
+// empty config.
+func loadRepoConfig(repo *git.Repository) (RepoConfig, error) {
+	var config RepoConfig
+	configPath, err := repoConfigPath(repo)
+	if err != nil {
+		return config, err
+	}
+	data, err := os.ReadFile(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %w", repoConfigFile, err)
+	}
+	return config, config.validate()
+}
+
+// repoConfigFor is loadRepoConfig for history walks, where a broken config
+// file should not fail the request: it is logged and ignored.
+func repoConfigFor(ctx context.Context, repo *git.Repository) RepoConfig {
+	config, err := loadRepoConfig(repo)
+	if err != nil {
+		loggerFrom(ctx).Warn("Ignoring invalid repository config", "error", err)
+		return RepoConfig{}
+	}
+	return config
+}
+
+func saveRepoConfig(repo *git.Repository, config RepoConfig) error {
+	configPath, err := repoConfigPath(repo)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := configPath + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, configPath)
+}
+
+// isIgnoredByPatterns reports whether file matches any of the ignore globs.
+func isIgnoredByPatterns(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.Trim(pattern, "/")
+		if file == pattern || strings.HasPrefix(file, pattern+"/") {
+			return true
+		}
+		if ok, _ := path.Match(pattern, file); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(file)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func compiledBotPatterns(config RepoConfig) []*regexp.Regexp {
+	if len(config.BotPatterns) == 0 {
+		return botPatterns
+	}
+	return compileBotPatterns(config.BotPatterns)
+}
+
+// RepoConfigHandler returns the repository's config on GET and replaces it
+// with the request body on PUT.
+func RepoConfigHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		http.Error(w, "Only GET and PUT methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		config, err := loadRepoConfig(repo)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read repository config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, r, config)
+		return
+	}
+
+	var config RepoConfig
+	if !decodeJSONBody(w, r, &config) {
+		return
+	}
+	if err := config.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if config.DefaultBranch != "" {
+		if _, err := resolveBranch(repo, config.DefaultBranch); err != nil {
+			http.Error(w, fmt.Sprintf("defaultBranch %q does not exist", config.DefaultBranch), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := saveRepoConfig(repo, config); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save repository config: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, r, config)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var errInvalidRevision = errors.New("invalid revision")
+
+// revisionStep is one parent hop of a relative revision: `~n` follows the
+// first parent n times and `^n` selects the nth parent (`^0` is the commit
+// itself).
+type revisionStep struct {
+	ancestor bool
+	n        int
+}
+
+// parseRevision splits a git-style relative revision such as HEAD~3, main^
+// or v1.0~2^2 into its base name and parent steps. A missing count means 1,
+// as on the git CLI.
+func parseRevision(rev string) (string, []revisionStep, error) {
+	i := strings.IndexAny(rev, "~^")
+	if i < 0 {
+		return rev, nil, nil
+	}
+	base := rev[:i]
+	if base == "" {
+		return "", nil, errInvalidRevision
+	}
+
+	var steps []revisionStep
+	rest := rev[i:]
+	for rest != "" {
+		step := revisionStep{ancestor: rest[0] == '~', n: 1}
+		if rest[0] != '~' && rest[0] != '^' {
+			return "", nil, errInvalidRevision
+		}
+		rest = rest[1:]
+
+		digits := 0
+		for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+			digits++
+		}
+		if digits > 0 {
+			n, err := strconv.Atoi(rest[:digits])
+			if err != nil {
+				return "", nil, errInvalidRevision
+			}
+			step.n = n
+			rest = rest[digits:]
+		}
+		steps = append(steps, step)
+	}
+	return base, steps, nil
+}
+
+func revisionErrorMessage(rev string, err error) string {
+	if errors.Is(err, errInvalidRevision) {
+		return fmt.Sprintf("Invalid revision %q", rev)
+	}
+	return fmt.Sprintf("Commit %q not found", rev)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// Server timeouts, in seconds; 0 disables one. The write timeout bounds how
+// long a handler may take to produce an ordinary response, so it must cover
+// the slowest analytics on the largest repository. Streaming responses lift
+// both deadlines for themselves with clearDeadlines, so neither needs to be
+// raised for long clones.
+var (
+	serverReadTimeout  = time.Duration(envInt("SERVER_READ_TIMEOUT_SECONDS", 30)) * time.Second
+	serverWriteTimeout = time.Duration(envInt("SERVER_WRITE_TIMEOUT_SECONDS", 300)) * time.Second
+	serverIdleTimeout  = time.Duration(envInt("SERVER_IDLE_TIMEOUT_SECONDS", 120)) * time.Second
+)
+
+func newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  serverReadTimeout,
+		WriteTimeout: serverWriteTimeout,
+		IdleTimeout:  serverIdleTimeout,
+	}
+}
+
+// clearDeadlines exempts a long-lived response, such as an event stream or
+// a long poll, from the server's read and write timeouts. Without it the
+// write deadline cuts a slow stream off mid-way, and the read deadline
+// cancels the request's context once it passes. The connection still closes
+// when the client goes away.
+func clearDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const defaultSessionGapMinutes = 120
+
+type AuthorSessions struct {
+	ContributorCount
+	Sessions int `json:"sessions"`
+	// AverageSessionMinutes is the mean time from the first to the last
+	// commit of a session, so a single-commit session counts as zero.
+	AverageSessionMinutes float64 `json:"averageSessionMinutes"`
+	CommitsPerSession     float64 `json:"commitsPerSession"`
+}
+
+type SessionsResponse struct {
+	GapMinutes int              `json:"gapMinutes"`
+	Authors    []AuthorSessions `json:"authors"`
+}
+
+// workSessions splits sorted times into runs whose consecutive commits are
+// less than gap apart, and returns how many there are and their total
+// length.
+func workSessions(times []time.Time, gap time.Duration) (sessions int, total time.Duration) {
+	for i := 0; i < len(times); {
+		start := i
+		for i++; i < len(times) && times[i].Sub(times[i-1]) < gap; i++ {
+		}
+		sessions++
+		total += times[i-1].Sub(times[start])
+	}
+	return sessions, total
+}
+
+// SessionsHandler reconstructs rough work sessions from commit times: each
+// author's commits are grouped into runs with gaps under gapMinutes (120 by
+// default), by author date. Authors are mailmap-resolved and listed by
+// commit count, and bots are always left out.
+func SessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	gapMinutes := defaultSessionGapMinutes
+	if value := r.URL.Query().Get("gapMinutes"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "gapMinutes must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		gapMinutes = n
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.ExcludeBots = true
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, r.URL.Query().Get("branch"), filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	authors := newAuthorCounter(loadMailmap(repo))
+	authors.anonymize = parseAnonymize(r)
+	times, err := authorCommitTimes(iter, filter, authors)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := SessionsResponse{GapMinutes: gapMinutes, Authors: make([]AuthorSessions, 0, authors.len())}
+	gap := time.Duration(gapMinutes) * time.Minute
+	for key, count := range authors.counts {
+		sessions, total := workSessions(times[key], gap)
+		resp.Authors = append(resp.Authors, AuthorSessions{
+			ContributorCount:      authors.listed(count),
+			Sessions:              sessions,
+			AverageSessionMinutes: total.Minutes() / float64(sessions),
+			CommitsPerSession:     float64(count.Commits) / float64(sessions),
+		})
+	}
+	sort.Slice(resp.Authors, func(i, j int) bool {
+		if resp.Authors[i].Commits != resp.Authors[j].Commits {
+			return resp.Authors[i].Commits > resp.Authors[j].Commits
+		}
+		return resp.Authors[i].Name < resp.Authors[j].Name
+	})
+
+	writeJSON(w, r, resp)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// shallowBoundaryHeader is set on every response for a shallow clone. It
+// lists the boundary commits, whose parents are missing, so clients can tell
+// that history-wide numbers stop there.
+const shallowBoundaryHeader = "X-Shallow-Boundary"
+
+// shallowBoundary returns the commits at the shallow boundary, read from the
+// repository's shallow file. It is empty for a complete clone.
+func shallowBoundary(repo *git.Repository) []string {
+	hashes, err := repo.Storer.Shallow()
+	if err != nil {
+		return nil
+	}
+	boundary := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		boundary = append(boundary, hash.String())
+	}
+	return boundary
+}
+
+// repoLog is repo.Log for every history walk. go-git fails with "object not
+// found" when a walk reaches the parents a shallow clone does not have, so
+// on a shallow clone those parents are marked as already seen and the walk
+// ends at the boundary instead. Only the options this server uses are
+// supported there: From or All, the default or committer-time order, and
+// Since/Until.
+func repoLog(repo *git.Repository, opts *git.LogOptions) (object.CommitIter, error) {
+	missing, err := missingShallowParents(repo)
+	if err != nil || len(missing) == 0 {
+		if err == nil {
+			return repo.Log(opts)
+		}
+		return nil, err
+	}
+
+	var walk func(*object.Commit) object.CommitIter
+	switch opts.Order {
+	case git.LogOrderDefault, git.LogOrderDFS:
+		walk = func(c *object.Commit) object.CommitIter {
+			return object.NewCommitPreorderIter(c, nil, missing)
+		}
+	case git.LogOrderCommitterTime:
+		walk = func(c *object.Commit) object.CommitIter {
+			return object.NewCommitIterCTime(c, nil, missing)
+		}
+	default:
+		return nil, fmt.Errorf("log order %v is not supported on a shallow clone", opts.Order)
+	}
+
+	var iter object.CommitIter
+	if opts.All {
+		if iter, err = object.NewCommitAllIter(repo.Storer, walk); err != nil {
+			return nil, err
+		}
+	} else {
+		from := opts.From
+		if from == plumbing.ZeroHash {
+			head, err := repo.Head()
+			if err != nil {
+				return nil, err
+			}
+			from = head.Hash()
+		}
+		commit, err := repo.CommitObject(from)
+		if err != nil {
+			return nil, err
+		}
+		iter = walk(commit)
+	}
+
+	if opts.Since != nil || opts.Until != nil {
+		iter = object.NewCommitLimitIterFromIter(iter, object.LogLimitOptions{Since: opts.Since, Until: opts.Until})
+	}
+	return iter, nil
+}
+
+// missingShallowParents lists the parents of the shallow boundary commits.
+func missingShallowParents(repo *git.Repository) ([]plumbing.Hash, error) {
+	boundary, err := repo.Storer.Shallow()
+	if err != nil {
+		return nil, err
+	}
+	var missing []plumbing.Hash
+	for _, hash := range boundary {
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			return nil, err
+		}
+		missing = append(missing, commit.ParentHashes...)
+	}
+	return missing, nil
+}
+
+func setShallowHeader(w http.ResponseWriter, repo *git.Repository) {
+	if boundary := shallowBoundary(repo); len(boundary) > 0 {
+		w.Header().Set(shallowBoundaryHeader, strings.Join(boundary, ","))
+	}
+}
+
+// refuseShallow rejects analytics that need the full history on a shallow
+// clone, where they would silently report numbers for the truncated history
+// only. allowShallow=true opts back in.
+func refuseShallow(w http.ResponseWriter, r *http.Request, repo *git.Repository) bool {
+	if r.URL.Query().Get("allowShallow") == "true" || len(shallowBoundary(repo)) == 0 {
+		return false
+	}
+	http.Error(w, "Repository is a shallow clone and this analysis needs its full history; pass allowShallow=true to run it on the truncated history", http.StatusConflict)
+	return true
+}
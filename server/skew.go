@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const (
+	defaultSkewThresholdHours = 24
+	defaultSkewSample         = 20
+)
+
+type SkewedCommit struct {
+	Hash          string  `json:"hash"`
+	Subject       string  `json:"subject"`
+	Author        string  `json:"author"`
+	AuthorDate    string  `json:"authorDate"`
+	CommitterDate string  `json:"committerDate"`
+	SkewHours     float64 `json:"skewHours"`
+
+	skew time.Duration
+}
+
+type DateSkewResponse struct {
+	ThresholdHours int            `json:"thresholdHours"`
+	TotalCommits   int            `json:"totalCommits"`
+	SkewedCommits  int            `json:"skewedCommits"`
+	Sample         []SkewedCommit `json:"sample"`
+}
+
+// DateSkewHandler reports commits whose committer date is more than
+// thresholdHours after their author date, which is what rebases and
+// cherry-picks of old work look like. The sample holds the largest skews.
+func DateSkewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	threshold := defaultSkewThresholdHours
+	if value := query.Get("thresholdHours"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "thresholdHours must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		threshold = n
+	}
+	sampleSize := defaultSkewSample
+	if value := query.Get("sample"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "sample must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		sampleSize = n
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	anonymize := parseAnonymize(r)
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, query.Get("branch"), filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	limit := time.Duration(threshold) * time.Hour
+	resp := DateSkewResponse{ThresholdHours: threshold}
+	skewed := []SkewedCommit{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		resp.TotalCommits++
+
+		skew := c.Committer.When.Sub(c.Author.When)
+		if skew <= limit {
+			return nil
+		}
+		resp.SkewedCommits++
+		skewed = append(skewed, SkewedCommit{
+			Hash:          c.Hash.String(),
+			Subject:       redactText(commitSubject(c.Message)),
+			Author:        authorName(c.Author, anonymize),
+			AuthorDate:    c.Author.When.Format(time.RFC3339),
+			CommitterDate: c.Committer.When.Format(time.RFC3339),
+			SkewHours:     skew.Hours(),
+			skew:          skew,
+		})
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	sort.SliceStable(skewed, func(i, j int) bool {
+		return skewed[i].skew > skewed[j].skew
+	})
+	if len(skewed) > sampleSize {
+		skewed = skewed[:sampleSize]
+	}
+	resp.Sample = skewed
+
+	writeJSON(w, r, resp)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	streamCommitDelay = time.Duration(envInt("STREAM_COMMIT_DELAY_MS", 100)) * time.Millisecond
+	sseFlushEvents    = envInt("SSE_FLUSH_EVENTS", 32)
+	sseFlushInterval  = time.Duration(envInt("SSE_FLUSH_INTERVAL_MS", 100)) * time.Millisecond
+
+	maxStreams        = int64(envInt("MAX_STREAMS", 100))
+	maxStreamsPerRepo = envInt("MAX_STREAMS_PER_REPO", 10)
+	streamRetryAfter  = envInt("STREAM_RETRY_AFTER_SECONDS", 5)
+)
+
+var (
+	activeStreams atomic.Int64
+	repoStreamsMu sync.Mutex
+	repoStreams   = map[string]int{}
+)
+
+// acquireStream reserves a streaming slot for repoID, honouring the global
+// and per-repo caps (0 disables either). When it returns false it has
+// already written a 503 with Retry-After; otherwise release must be called
+// once the stream ends.
+func acquireStream(w http.ResponseWriter, repoID string) (release func(), ok bool) {
+	if n := activeStreams.Add(1); maxStreams > 0 && n > maxStreams {
+		activeStreams.Add(-1)
+		rejectStream(w, "Too many concurrent streams")
+		return nil, false
+	}
+
+	repoStreamsMu.Lock()
+	if maxStreamsPerRepo > 0 && repoStreams[repoID] >= maxStreamsPerRepo {
+		repoStreamsMu.Unlock()
+		activeStreams.Add(-1)
+		rejectStream(w, "Too many concurrent streams for this repository")
+		return nil, false
+	}
+	repoStreams[repoID]++
+	repoStreamsMu.Unlock()
+
+	return func() {
+		repoStreamsMu.Lock()
+		if repoStreams[repoID]--; repoStreams[repoID] <= 0 {
+			delete(repoStreams, repoID)
+		}
+		repoStreamsMu.Unlock()
+		activeStreams.Add(-1)
+	}, true
+}
+
+func rejectStream(w http.ResponseWriter, message string) {
+	w.Header().Set("Retry-After", strconv.Itoa(streamRetryAfter))
+	http.Error(w, message, http.StatusServiceUnavailable)
+}
+
+// sseBatcher wraps a streaming response so that the per-event Flush done by
+// sendSSEMessage only reaches the client once maxEvents events are pending
+// or interval has passed since the first unflushed one. Close must be called
+// when the stream ends to deliver the final batch.
+type sseBatcher struct {
+	http.ResponseWriter
+
+	mu        sync.Mutex
+	maxEvents int
+	interval  time.Duration
+	pending   int
+	timer     *time.Timer
+	closed    bool
+}
+
+func newSSEBatcher(w http.ResponseWriter) *sseBatcher {
+	return &sseBatcher{ResponseWriter: w, maxEvents: sseFlushEvents, interval: sseFlushInterval}
+}
+
+func (b *sseBatcher) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ResponseWriter.Write(p)
+}
+
+func (b *sseBatcher) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending++
+	if b.closed || b.pending >= b.maxEvents || b.interval <= 0 {
+		b.flushLocked()
+		return
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.interval, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if !b.closed {
+				b.flushLocked()
+			}
+		})
+	}
+}
+
+// Close flushes anything still pending and makes every later event flush
+// immediately.
+func (b *sseBatcher) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked()
+	b.closed = true
+}
+
+func (b *sseBatcher) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if b.pending == 0 {
+		return
+	}
+	b.pending = 0
+	if f, ok := b.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (b *sseBatcher) Unwrap() http.ResponseWriter {
+	return b.ResponseWriter
+}
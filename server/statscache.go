@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sync/atomic"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// statsCacheEntries bounds the per-commit stats cache. 0 disables it.
+var statsCacheEntries = envInt("STATS_CACHE_ENTRIES", 100000)
+
+// statsCache keeps the file modifications of commits by hash. A commit hash
+// names its content and its parents, so an entry never goes stale: new
+// history only adds hashes, which are computed on the first request that
+// walks them while every older entry stays valid. Entries are evicted least
+// recently used first.
+type statsCache struct {
+	entries *lruCache[plumbing.Hash, []FileModification]
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+var commitStats = newStatsCache(statsCacheEntries)
+
+func newStatsCache(limit int) *statsCache {
+	return &statsCache{entries: newLRUCache[plumbing.Hash, []FileModification](limit)}
+}
+
+// get returns the cached modifications for hash. The slice is shared and
+// must not be modified.
+func (s *statsCache) get(hash plumbing.Hash) ([]FileModification, bool) {
+	modifications, ok := s.entries.get(hash)
+	if ok {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+	return modifications, ok
+}
+
+func (s *statsCache) put(hash plumbing.Hash, modifications []FileModification) {
+	s.entries.put(hash, modifications)
+}
+
+// StatsCacheStatus is reported by /readyz.
+type StatsCacheStatus struct {
+	Entries int   `json:"entries"`
+	Limit   int   `json:"limit"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+}
+
+func (s *statsCache) status() StatsCacheStatus {
+	return StatsCacheStatus{
+		Entries: s.entries.len(),
+		Limit:   s.entries.limit,
+		Hits:    s.hits.Load(),
+		Misses:  s.misses.Load(),
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestStatsCache(t *testing.T) {
+	a, b, c := plumbing.NewHash("aa"), plumbing.NewHash("bb"), plumbing.NewHash("cc")
+	mods := func(file string) []FileModification { return []FileModification{{File: file, Additions: 1}} }
+
+	cache := newStatsCache(2)
+	cache.put(a, mods("a.go"))
+	cache.put(b, mods("b.go"))
+	if got, ok := cache.get(a); !ok || got[0].File != "a.go" {
+		t.Fatalf("get(a) = %v, %t", got, ok)
+	}
+	// a was used more recently than b, so b is evicted.
+	cache.put(c, mods("c.go"))
+	if _, ok := cache.get(b); ok {
+		t.Error("b survived eviction")
+	}
+	for _, hash := range []plumbing.Hash{a, c} {
+		if _, ok := cache.get(hash); !ok {
+			t.Errorf("%s evicted", hash)
+		}
+	}
+	want := StatsCacheStatus{Entries: 2, Limit: 2, Hits: 3, Misses: 1}
+	if got := cache.status(); got != want {
+		t.Errorf("status = %+v, want %+v", got, want)
+	}
+
+	disabled := newStatsCache(0)
+	disabled.put(a, mods("a.go"))
+	if _, ok := disabled.get(a); ok {
+		t.Error("disabled cache stored an entry")
+	}
+	if got := disabled.status(); got.Entries != 0 || got.Misses != 1 {
+		t.Errorf("disabled status = %+v", got)
+	}
+}
+
+func TestStatsCacheAcrossWalks(t *testing.T) {
+	f := generatedHistory(t, 10)
+	previous := commitStats
+	commitStats = newStatsCache(100)
+	t.Cleanup(func() { commitStats = previous })
+
+	walk := func() {
+		t.Helper()
+		if w := serve(FileModificationsHandler, http.MethodGet, "/files?repoId="+f.ID, nil); w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+	}
+	walk()
+	if got, want := commitStats.status(), (StatsCacheStatus{Entries: 10, Limit: 100, Misses: 10}); got != want {
+		t.Fatalf("after the first walk: status = %+v, want %+v", got, want)
+	}
+
+	// Only the new commit is diffed; the rest come from the cache.
+	f.commit(fixtureCommit{
+		Message: "Commit 10\n",
+		Name:    "Alice",
+		Email:   "alice@example.com",
+		When:    time.Date(2026, 1, 1, 0, 10, 0, 0, time.UTC),
+		Files:   map[string]string{"pkg0/file.go": "package pkg\n\n// revision 10\n"},
+	})
+	walk()
+	if got, want := commitStats.status(), (StatsCacheStatus{Entries: 11, Limit: 100, Hits: 10, Misses: 11}); got != want {
+		t.Errorf("after the second walk: status = %+v, want %+v", got, want)
+	}
+}
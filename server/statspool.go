@@ -0,0 +1,141 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// statsBytesPerCommit is a rough upper bound on what one commit being diffed
+// holds in memory (two trees and the changed blobs), used to size the
+// in-flight cap from GOMEMLIMIT.
+const statsBytesPerCommit = 4 << 20
+
+var (
+	// statsWorkers is how many commits are diffed at once.
+	statsWorkers = max(1, envInt("STATS_WORKERS", runtime.NumCPU()))
+	// statsMaxInFlight bounds how far the log walk may run ahead of the
+	// commit handed to the caller. Every commit in flight keeps its trees
+	// and stats alive, so this is what bounds memory.
+	statsMaxInFlight = max(statsWorkers, envInt("STATS_MAX_IN_FLIGHT", defaultStatsInFlight()))
+)
+
+// defaultStatsInFlight allows a quarter of GOMEMLIMIT worth of commits in
+// flight, between 4 per worker and 1024, or 4 per worker without a limit.
+func defaultStatsInFlight() int {
+	limit := debug.SetMemoryLimit(-1)
+	if limit == math.MaxInt64 {
+		return 4 * statsWorkers
+	}
+	return min(1024, max(4*statsWorkers, int(limit/4/statsBytesPerCommit)))
+}
+
+func commitStatsWith(repo *git.Repository, hash plumbing.Hash, keep func(*object.Commit) bool) statsResult {
+	c, err := repo.CommitObject(hash)
+	if err != nil {
+		return statsResult{keep: true, err: err}
+	}
+	result := statsResult{commit: c, keep: keep(c)}
+	if result.keep {
+		result.modifications, result.err = commitModifications(c)
+	}
+	return result
+}
+
+type statsResult struct {
+	commit        *object.Commit
+	keep          bool
+	modifications []FileModification
+	err           error
+}
+
+// forEachCommitStats walks iter and calls fn, in walk order, with every
+// commit keep accepts and its file modifications. keep and the diffs run on
+// statsWorkers goroutines. The walk blocks once statsMaxInFlight commits are
+// waiting for fn, so a slow consumer holds it back instead of letting it
+// load the whole history. fn may return storer.ErrStop to end the walk.
+//
+// go-git repositories are not safe for concurrent use, so every worker opens
+// its own handle on repoID and reloads the commits it is given from it. fn
+// runs while workers still use those handles and must only read the commit's
+// fields, not objects reachable from it.
+func forEachCommitStats(repoID string, iter object.CommitIter, keep func(*object.Commit) bool, fn func(*object.Commit, []FileModification) error) error {
+	handles := make([]*git.Repository, statsWorkers)
+	for i := range handles {
+		repo, err := openRepository(filepath.Join(reposDir, repoID))
+		if err != nil {
+			return err
+		}
+		handles[i] = repo
+	}
+
+	type job struct {
+		commit *object.Commit
+		out    chan<- statsResult
+	}
+
+	// pending holds one result channel per commit in walk order; its
+	// capacity is the in-flight cap.
+	pending := make(chan chan statsResult, statsMaxInFlight)
+	jobs := make(chan job)
+	done := make(chan struct{})
+
+	var workers sync.WaitGroup
+	for _, repo := range handles {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for j := range jobs {
+				j.out <- commitStatsWith(repo, j.commit.Hash, keep)
+			}
+		}()
+	}
+
+	var walkErr error
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		walkErr = iter.ForEach(func(c *object.Commit) error {
+			out := make(chan statsResult, 1)
+			select {
+			case pending <- out:
+			case <-done:
+				return storer.ErrStop
+			}
+			jobs <- job{commit: c, out: out}
+			return nil
+		})
+	}()
+
+	// After an error or ErrStop the loop keeps draining, so the walk sees
+	// done and every goroutine exits before returning.
+	var err error
+	for out := range pending {
+		result := <-out
+		if err != nil || !result.keep {
+			continue
+		}
+		if err = result.err; err == nil {
+			err = fn(result.commit, result.modifications)
+		}
+		if err != nil {
+			close(done)
+		}
+	}
+	workers.Wait()
+
+	if err == storer.ErrStop {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	return walkErr
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// countingIter counts the commits the log walk has produced.
+type countingIter struct {
+	object.CommitIter
+	walked atomic.Int64
+}
+
+func (c *countingIter) ForEach(fn func(*object.Commit) error) error {
+	return c.CommitIter.ForEach(func(commit *object.Commit) error {
+		c.walked.Add(1)
+		return fn(commit)
+	})
+}
+
+// generatedHistory builds a fixture with n commits, each changing one of a
+// handful of files.
+func generatedHistory(tb testing.TB, n int) *fixtureRepo {
+	commits := make([]fixtureCommit, n)
+	for i := range commits {
+		commits[i] = fixtureCommit{
+			Message: fmt.Sprintf("Commit %d\n", i),
+			When:    time.Date(2026, 1, 1, 0, i, 0, 0, time.UTC),
+			Files:   map[string]string{fmt.Sprintf("pkg%d/file.go", i%5): fmt.Sprintf("package pkg\n\n// revision %d\n", i)},
+		}
+	}
+	return newFixtureRepo(tb, commits...)
+}
+
+func TestForEachCommitStatsBoundsInFlight(t *testing.T) {
+	f := generatedHistory(t, 60)
+	previousWorkers, previousInFlight := statsWorkers, statsMaxInFlight
+	statsWorkers, statsMaxInFlight = 2, 4
+	t.Cleanup(func() { statsWorkers, statsMaxInFlight = previousWorkers, previousInFlight })
+
+	log, err := commitLog(context.Background(), f.Repo, "", nil)
+	if err != nil {
+		t.Fatalf("log: %v", err)
+	}
+	iter := &countingIter{CommitIter: log}
+
+	// The walk may hold one commit it cannot queue yet on top of the
+	// statsMaxInFlight queued ones.
+	limit := int64(statsMaxInFlight + 1)
+	var delivered, maxAhead int64
+	keep := func(*object.Commit) bool { return true }
+	err = forEachCommitStats(f.ID, iter, keep, func(*object.Commit, []FileModification) error {
+		delivered++
+		// A slow consumer lets the walk run as far ahead as it can.
+		time.Sleep(time.Millisecond)
+		maxAhead = max(maxAhead, iter.walked.Load()-delivered)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("forEachCommitStats: %v", err)
+	}
+	if delivered != 60 {
+		t.Fatalf("delivered %d commits, want 60", delivered)
+	}
+	if maxAhead > limit {
+		t.Errorf("walk ran %d commits ahead of the consumer, cap is %d", maxAhead, limit)
+	}
+	t.Logf("walk ran at most %d commits ahead", maxAhead)
+}
+
+func BenchmarkForEachCommitStats(b *testing.B) {
+	f := generatedHistory(b, 200)
+	// Keep the stats cache from answering every iteration after the first.
+	previousCache := commitStats
+	b.Cleanup(func() { commitStats = previousCache })
+	keep := func(*object.Commit) bool { return true }
+
+	b.ReportAllocs()
+	for b.Loop() {
+		commitStats = newStatsCache(0)
+		iter, err := commitLog(context.Background(), f.Repo, "", nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		err = forEachCommitStats(f.ID, iter, keep, func(*object.Commit, []FileModification) error { return nil })
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
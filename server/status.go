@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+)
+
+type StatusEntry struct {
+	Path string `json:"path"`
+	// Staging and Worktree are git's short status codes for the index and
+	// the working tree: M, A, D, R, C, U, ? or a space when unchanged.
+	Staging  string `json:"staging"`
+	Worktree string `json:"worktree"`
+}
+
+type WorktreeStatus struct {
+	Clean      bool          `json:"clean"`
+	Modified   []string      `json:"modified"`
+	Added      []string      `json:"added"`
+	Deleted    []string      `json:"deleted"`
+	Renamed    []string      `json:"renamed"`
+	Untracked  []string      `json:"untracked"`
+	Conflicted []string      `json:"conflicted"`
+	Files      []StatusEntry `json:"files"`
+}
+
+// StatusHandler is `git status` for a repository's working tree: what has
+// changed relative to HEAD, staged or not, plus untracked files. Bare
+// repositories have no working tree and are rejected.
+func StatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	worktree, err := repo.Worktree()
+	if errors.Is(err, git.ErrIsBareRepository) {
+		http.Error(w, "Repository is bare and has no working tree", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to open working tree: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get status: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := WorktreeStatus{
+		Clean:      status.IsClean(),
+		Modified:   []string{},
+		Added:      []string{},
+		Deleted:    []string{},
+		Renamed:    []string{},
+		Untracked:  []string{},
+		Conflicted: []string{},
+		Files:      []StatusEntry{},
+	}
+	paths := make([]string, 0, len(status))
+	for path := range status {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		file := status[path]
+		resp.Files = append(resp.Files, StatusEntry{
+			Path:     path,
+			Staging:  string(file.Staging),
+			Worktree: string(file.Worktree),
+		})
+		switch {
+		case file.Staging == git.UpdatedButUnmerged || file.Worktree == git.UpdatedButUnmerged:
+			resp.Conflicted = append(resp.Conflicted, path)
+		case file.Worktree == git.Untracked:
+			resp.Untracked = append(resp.Untracked, path)
+		case file.Staging == git.Deleted || file.Worktree == git.Deleted:
+			resp.Deleted = append(resp.Deleted, path)
+		case file.Staging == git.Added:
+			resp.Added = append(resp.Added, path)
+		case file.Staging == git.Renamed || file.Staging == git.Copied:
+			resp.Renamed = append(resp.Renamed, path)
+		default:
+			resp.Modified = append(resp.Modified, path)
+		}
+	}
+
+	writeJSON(w, r, resp)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type Summary struct {
+	RepoID               string         `json:"repoId"`
+	Head                 string         `json:"head"`
+	CurrentBranch        string         `json:"currentBranch,omitempty"`
+	DetachedHead         bool           `json:"detachedHead"`
+	Shallow              bool           `json:"shallow"`
+	ShallowBoundary      []string       `json:"shallowBoundary,omitempty"`
+	TotalCommits         int            `json:"totalCommits"`
+	Branches             int            `json:"branches"`
+	Tags                 int            `json:"tags"`
+	Contributors         int            `json:"contributors"`
+	ExcludedContributors int            `json:"excludedContributors"`
+	Languages            []LanguageStat `json:"languages"`
+	PrimaryLanguage      string         `json:"primaryLanguage"`
+	License              *LicenseInfo   `json:"license"`
+}
+
+func SummaryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	minCommits, err := parseMinCommits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	summary, _, err := buildSummary(r.Context(), repo, repoID, filter, minCommits)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to build summary: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, summary)
+}
+
+// buildSummary computes the summary of a repository whose filter is already
+// bound. The author counter is returned too so callers can merge
+// contributors across repositories.
+func buildSummary(ctx context.Context, repo *git.Repository, repoID string, filter commitFilter, minCommits int) (Summary, *authorCounter, error) {
+	ref, err := repo.Head()
+	if err != nil {
+		return Summary{}, nil, fmt.Errorf("get HEAD reference: %w", err)
+	}
+
+	summary := Summary{RepoID: repoID, Head: ref.Hash().String()}
+	summary.ShallowBoundary = shallowBoundary(repo)
+	summary.Shallow = len(summary.ShallowBoundary) > 0
+	summary.CurrentBranch, summary.DetachedHead, err = headState(repo)
+	if err != nil {
+		return summary, nil, fmt.Errorf("get HEAD reference: %w", err)
+	}
+
+	branches, err := getBranches(repo)
+	if err != nil {
+		return summary, nil, fmt.Errorf("get branches: %w", err)
+	}
+	summary.Branches = len(branches)
+
+	tags, err := repo.Tags()
+	if err != nil {
+		return summary, nil, fmt.Errorf("get tags: %w", err)
+	}
+	err = tags.ForEach(func(*plumbing.Reference) error {
+		summary.Tags++
+		return nil
+	})
+	if err != nil {
+		return summary, nil, fmt.Errorf("get tags: %w", err)
+	}
+
+	iter, err := commitLog(ctx, repo, "", filter.logOptions())
+	if err != nil {
+		return summary, nil, fmt.Errorf("get commit logs: %w", err)
+	}
+	authors := newAuthorCounter(loadMailmap(repo))
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		summary.TotalCommits++
+		authors.add(c.Author)
+		return nil
+	})
+	if err != nil {
+		return summary, nil, fmt.Errorf("process commits: %w", err)
+	}
+	contributors, excluded := authors.regular(minCommits)
+	summary.Contributors = len(contributors)
+	summary.ExcludedContributors = excluded
+
+	summary.Languages, err = headLanguages(repo)
+	if err != nil {
+		return summary, nil, fmt.Errorf("compute languages: %w", err)
+	}
+	summary.PrimaryLanguage = primaryLanguage(summary.Languages)
+
+	summary.License, err = headLicense(repo)
+	if err != nil {
+		return summary, nil, fmt.Errorf("detect license: %w", err)
+	}
+	return summary, authors, nil
+}
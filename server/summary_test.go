@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestSummaryDetachedHead(t *testing.T) {
+	tests := []struct {
+		name         string
+		checkoutTag  bool
+		wantBranch   string
+		wantDetached bool
+		// wantHead indexes f.Hashes.
+		wantHead    int
+		wantCommits int
+	}{
+		{name: "on branch", wantBranch: "master", wantHead: 2, wantCommits: 3},
+		{name: "tag checked out", checkoutTag: true, wantDetached: true, wantHead: 0, wantCommits: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixtureRepo(t,
+				fixtureCommit{Message: "Release 1.0\n", Files: map[string]string{"a.txt": "1\n"}},
+				fixtureCommit{Message: "Work\n", Files: map[string]string{"a.txt": "2\n"}},
+				fixtureCommit{Message: "More work\n", Files: map[string]string{"a.txt": "3\n"}},
+			)
+			f.tag("v1.0", f.Hashes[0])
+			if tt.checkoutTag {
+				wt, err := f.Repo.Worktree()
+				if err != nil {
+					t.Fatalf("worktree: %v", err)
+				}
+				if err := wt.Checkout(&git.CheckoutOptions{Hash: f.Hashes[0]}); err != nil {
+					t.Fatalf("checkout v1.0: %v", err)
+				}
+			}
+
+			w := serve(SummaryHandler, http.MethodGet, "/summary?repoId="+f.ID, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			var summary Summary
+			if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if summary.DetachedHead != tt.wantDetached {
+				t.Errorf("detachedHead = %t, want %t", summary.DetachedHead, tt.wantDetached)
+			}
+			if summary.CurrentBranch != tt.wantBranch {
+				t.Errorf("currentBranch = %q, want %q", summary.CurrentBranch, tt.wantBranch)
+			}
+			if summary.Head != f.Hashes[tt.wantHead].String() {
+				t.Errorf("head = %s, want %s", summary.Head, f.Hashes[tt.wantHead])
+			}
+			if summary.TotalCommits != tt.wantCommits {
+				t.Errorf("totalCommits = %d, want %d", summary.TotalCommits, tt.wantCommits)
+			}
+		})
+	}
+}
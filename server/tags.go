@@ -0,0 +1,63 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type taggedCommit struct {
+	Name   string
+	Commit *object.Commit
+}
+
+// listTags returns every tag that points at a commit, oldest first by commit
+// date. Annotated tags are peeled to their target commit.
+func listTags(repo *git.Repository) ([]taggedCommit, error) {
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []taggedCommit
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		hash := ref.Hash()
+		if tag, err := repo.TagObject(hash); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				return nil
+			}
+			hash = commit.Hash
+		}
+
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			return nil
+		}
+		tags = append(tags, taggedCommit{Name: ref.Name().Short(), Commit: commit})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].Commit.Committer.When.Before(tags[j].Commit.Committer.When)
+	})
+	return tags, nil
+}
+
+func ancestors(repo *git.Repository, from plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	seen := map[plumbing.Hash]bool{}
+	iter, err := repoLog(repo, &git.LogOptions{From: from})
+	if err != nil {
+		return nil, err
+	}
+	err = iter.ForEach(func(c *object.Commit) error {
+		seen[c.Hash] = true
+		return nil
+	})
+	return seen, err
+}
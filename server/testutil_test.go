@@ -0,0 +1,165 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// fixtureURL is the origin of every fixture repository, so POST /repo with
+// it reuses the fixture instead of cloning.
+const fixtureURL = "https://example.com/acme/fixture.git"
+
+// fixtureCommit is one commit of a fixture repository. Files are written
+// with the given contents and Remove deletes paths before committing.
+type fixtureCommit struct {
+	Message string
+	Name    string
+	Email   string
+	When    time.Time
+	Files   map[string]string
+	Remove  []string
+}
+
+type fixtureRepo struct {
+	ID     string
+	Path   string
+	Repo   *git.Repository
+	Hashes []plumbing.Hash
+	t      testing.TB
+}
+
+// newFixtureRepo points reposDir at a temp directory and builds a repository
+// in it from commits, oldest first. Empty Name, Email and When default to a
+// fixed author and one day per commit from 2026-01-01.
+func newFixtureRepo(t testing.TB, commits ...fixtureCommit) *fixtureRepo {
+	t.Helper()
+	dir := t.TempDir()
+	previous := reposDir
+	reposDir = dir
+	t.Cleanup(func() { reposDir = previous })
+
+	id := repoIDForURL(fixtureURL)
+	path := filepath.Join(dir, id)
+	repo, err := git.PlainInit(path, false)
+	if err != nil {
+		t.Fatalf("init fixture: %v", err)
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{fixtureURL}}); err != nil {
+		t.Fatalf("add origin: %v", err)
+	}
+
+	f := &fixtureRepo{ID: id, Path: path, Repo: repo, t: t}
+	for i, c := range commits {
+		if c.Name == "" {
+			c.Name, c.Email = "Alice", "alice@example.com"
+		}
+		if c.When.IsZero() {
+			c.When = time.Date(2026, 1, 1+i, 10, 0, 0, 0, time.UTC)
+		}
+		f.commit(c)
+	}
+	return f
+}
+
+// commit adds c on top of the current HEAD.
+func (f *fixtureRepo) commit(c fixtureCommit) plumbing.Hash {
+	f.t.Helper()
+	wt, err := f.Repo.Worktree()
+	if err != nil {
+		f.t.Fatalf("worktree: %v", err)
+	}
+	for name, contents := range c.Files {
+		full := filepath.Join(f.Path, name)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			f.t.Fatalf("mkdir %s: %v", name, err)
+		}
+		if err := os.WriteFile(full, []byte(contents), 0o644); err != nil {
+			f.t.Fatalf("write %s: %v", name, err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			f.t.Fatalf("add %s: %v", name, err)
+		}
+	}
+	for _, name := range c.Remove {
+		if _, err := wt.Remove(name); err != nil {
+			f.t.Fatalf("remove %s: %v", name, err)
+		}
+	}
+	sig := &object.Signature{Name: c.Name, Email: c.Email, When: c.When}
+	hash, err := wt.Commit(c.Message, &git.CommitOptions{Author: sig, Committer: sig, AllowEmptyCommits: true})
+	if err != nil {
+		f.t.Fatalf("commit %q: %v", c.Message, err)
+	}
+	f.Hashes = append(f.Hashes, hash)
+	return hash
+}
+
+// branch creates a branch at the current HEAD without checking it out.
+func (f *fixtureRepo) branch(name string) {
+	f.t.Helper()
+	f.refAtHead(plumbing.NewBranchReferenceName(name))
+}
+
+// remoteBranch creates a remote-tracking branch at the current HEAD, as
+// fetching name from remote would.
+func (f *fixtureRepo) remoteBranch(remote, name string) {
+	f.t.Helper()
+	f.refAtHead(plumbing.NewRemoteReferenceName(remote, name))
+}
+
+func (f *fixtureRepo) refAtHead(name plumbing.ReferenceName) {
+	f.t.Helper()
+	head, err := f.Repo.Head()
+	if err != nil {
+		f.t.Fatalf("head: %v", err)
+	}
+	if err := f.Repo.Storer.SetReference(plumbing.NewHashReference(name, head.Hash())); err != nil {
+		f.t.Fatalf("ref %s: %v", name, err)
+	}
+}
+
+// tag creates a lightweight tag at hash.
+func (f *fixtureRepo) tag(name string, hash plumbing.Hash) {
+	f.t.Helper()
+	if _, err := f.Repo.CreateTag(name, hash, nil); err != nil {
+		f.t.Fatalf("tag %s: %v", name, err)
+	}
+}
+
+// serve runs handler on one request and returns the recorded response.
+func serve(handler http.HandlerFunc, method, target string, body io.Reader) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(method, target, body))
+	return w
+}
+
+// sseEvents splits an event stream body into event name and data pairs, in
+// order.
+func sseEvents(body string) [][2]string {
+	var events [][2]string
+	for _, block := range strings.Split(body, "\n\n") {
+		var event [2]string
+		for _, line := range strings.Split(block, "\n") {
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event[0] = name
+			} else if data, ok := strings.CutPrefix(line, "data: "); ok {
+				event[1] = data
+			}
+		}
+		if event[0] != "" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+)
+
+// revertPattern matches the line git revert writes into the message.
+var revertPattern = regexp.MustCompile(`This reverts commit ([0-9a-f]{40})`)
+
+// revertedHash returns the commit a revert commit undoes.
+func revertedHash(message string) (plumbing.Hash, bool) {
+	m := revertPattern.FindStringSubmatch(message)
+	if m == nil {
+		return plumbing.ZeroHash, false
+	}
+	return plumbing.NewHash(m[1]), true
+}
+
+type ChainCommit struct {
+	Hash    string `json:"hash"`
+	Subject string `json:"subject"`
+	Author  string `json:"author"`
+	Date    string `json:"date"`
+}
+
+func newChainCommit(c *object.Commit, anonymize bool) ChainCommit {
+	return ChainCommit{
+		Hash:    c.Hash.String(),
+		Subject: redactText(commitSubject(c.Message)),
+		Author:  authorName(c.Author, anonymize),
+		Date:    c.Author.When.Format(time.RFC3339),
+	}
+}
+
+type FileLifecycleEvent struct {
+	Action string `json:"action"`
+	Hash   string `json:"hash"`
+	Date   string `json:"date"`
+
+	when time.Time
+}
+
+type ThrashingFile struct {
+	File string `json:"file"`
+	// Readded counts how often the file came back after being deleted.
+	Readded int                  `json:"readded"`
+	Events  []FileLifecycleEvent `json:"events"`
+}
+
+type ChurnAnomalies struct {
+	Files []ThrashingFile `json:"files"`
+	// RevertChains are a commit, its revert, the revert of that and so
+	// on, oldest first. Only chains with a revert of a revert are listed,
+	// since a single revert is not thrashing.
+	RevertChains [][]ChainCommit `json:"revertChains"`
+}
+
+// fileLifecycle returns the files c adds and deletes relative to its first
+// parent. Only the tree shapes are compared, without diffing contents. It
+// returns nothing for a commit whose parent is missing from a shallow clone.
+func fileLifecycle(c *object.Commit) (added, deleted []string, err error) {
+	changes, err := commitChanges(context.Background(), c)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, change := range changes {
+		action, err := change.Action()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch action {
+		case merkletrie.Insert:
+			added = append(added, change.To.Name)
+		case merkletrie.Delete:
+			deleted = append(deleted, change.From.Name)
+		}
+	}
+	return added, deleted, nil
+}
+
+// ChurnAnomaliesHandler flags thrashing: files deleted and added back at
+// least minReadds times (default 1), and chains of reverts that were
+// themselves reverted. Merge commits are skipped for the file part, since
+// their diff against the first parent repeats what the merged branch did.
+func ChurnAnomaliesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	minReadds := 1
+	if value := r.URL.Query().Get("minReadds"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "minReadds must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		minReadds = n
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, r.URL.Query().Get("branch"), filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	events := map[string][]FileLifecycleEvent{}
+	commits := map[plumbing.Hash]*object.Commit{}
+	reverts := map[plumbing.Hash]plumbing.Hash{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		commits[c.Hash] = c
+		if target, ok := revertedHash(c.Message); ok {
+			reverts[c.Hash] = target
+		}
+		if c.NumParents() > 1 {
+			return nil
+		}
+
+		added, deleted, err := fileLifecycle(c)
+		if err != nil {
+			return err
+		}
+		record := func(files []string, action string) {
+			for _, file := range files {
+				if filter.includesFile(file) {
+					events[file] = append(events[file], FileLifecycleEvent{
+						Action: action,
+						Hash:   c.Hash.String(),
+						Date:   c.Author.When.Format(time.RFC3339),
+						when:   c.Author.When,
+					})
+				}
+			}
+		}
+		record(added, "added")
+		record(deleted, "deleted")
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := ChurnAnomalies{Files: []ThrashingFile{}, RevertChains: [][]ChainCommit{}}
+	for file, history := range events {
+		// The walk is newest first; reversing before the stable sort keeps
+		// commits made within the same second in parent-child order.
+		slices.Reverse(history)
+		sort.SliceStable(history, func(i, j int) bool {
+			return history[i].when.Before(history[j].when)
+		})
+		readded := 0
+		for i := 1; i < len(history); i++ {
+			if history[i].Action == "added" && history[i-1].Action == "deleted" {
+				readded++
+			}
+		}
+		if readded >= minReadds {
+			resp.Files = append(resp.Files, ThrashingFile{File: file, Readded: readded, Events: history})
+		}
+	}
+	sort.Slice(resp.Files, func(i, j int) bool {
+		if resp.Files[i].Readded != resp.Files[j].Readded {
+			return resp.Files[i].Readded > resp.Files[j].Readded
+		}
+		return resp.Files[i].File < resp.Files[j].File
+	})
+
+	resp.RevertChains = revertChains(commits, reverts, parseAnonymize(r))
+
+	writeJSON(w, r, resp)
+}
+
+// revertChains follows reverts back from every revert nothing else reverts
+// and keeps the chains holding at least two reverts, i.e. a revert that was
+// itself reverted. Commits outside the walk end a chain.
+func revertChains(commits map[plumbing.Hash]*object.Commit, reverts map[plumbing.Hash]plumbing.Hash, anonymize bool) [][]ChainCommit {
+	reverted := map[plumbing.Hash]bool{}
+	for _, target := range reverts {
+		reverted[target] = true
+	}
+
+	var chains [][]*object.Commit
+	for head := range reverts {
+		if reverted[head] {
+			continue
+		}
+		var chain []*object.Commit
+		revertCount := 0
+		seen := map[plumbing.Hash]bool{}
+		for hash := head; commits[hash] != nil && !seen[hash]; {
+			seen[hash] = true
+			chain = append([]*object.Commit{commits[hash]}, chain...)
+			target, ok := reverts[hash]
+			if !ok {
+				break
+			}
+			revertCount++
+			hash = target
+		}
+		if revertCount >= 2 {
+			chains = append(chains, chain)
+		}
+	}
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i][0].Author.When.Before(chains[j][0].Author.When)
+	})
+
+	result := make([][]ChainCommit, len(chains))
+	for i, chain := range chains {
+		for _, c := range chain {
+			result[i] = append(result[i], newChainCommit(c, anonymize))
+		}
+	}
+	return result
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+const maxTouchingPaths = 50
+
+type TouchingRequest struct {
+	RepoID string   `json:"repoId"`
+	Paths  []string `json:"paths"`
+	// Match is "all" (the default) to keep commits that touch every path,
+	// or "any" to keep commits that touch at least one.
+	Match  string `json:"match,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	// Limit stops the walk after this many commits; 0 returns them all.
+	Limit int `json:"limit,omitempty"`
+}
+
+type TouchingCommit struct {
+	Commit
+	// TouchedPaths are the requested paths the commit changed, in request
+	// order.
+	TouchedPaths []string `json:"touchedPaths"`
+}
+
+// CommitsTouchingHandler finds the commits that changed all, or any, of a
+// set of paths, such as an API and its client. Paths are prefixes like the
+// path filter: a directory matches everything under it. Commits are compared
+// against their first parent, and the usual commit filters in the query
+// string still apply.
+func CommitsTouchingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	commitOpts, err := parseCommitOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fields, err := parseFields(r, TouchingCommit{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req TouchingRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if len(req.Paths) == 0 {
+		http.Error(w, "paths is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Paths) > maxTouchingPaths {
+		http.Error(w, fmt.Sprintf("at most %d paths are allowed", maxTouchingPaths), http.StatusBadRequest)
+		return
+	}
+	paths := make([]string, len(req.Paths))
+	for i, p := range req.Paths {
+		if paths[i] = strings.Trim(p, "/"); paths[i] == "" {
+			http.Error(w, "paths must not be empty", http.StatusBadRequest)
+			return
+		}
+	}
+	matchAll := true
+	switch req.Match {
+	case "", "all":
+	case "any":
+		matchAll = false
+	default:
+		http.Error(w, "match must be all or any", http.StatusBadRequest)
+		return
+	}
+	if req.Limit < 0 {
+		http.Error(w, "limit must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := openRequestedRepo(w, req.RepoID)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	iter, err := commitLog(r.Context(), repo, req.Branch, filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	commits := []TouchingCommit{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		touched := touchedPaths(c, paths)
+		if len(touched) == 0 || matchAll && len(touched) < len(paths) {
+			return nil
+		}
+		commits = append(commits, TouchingCommit{Commit: newCommit(c, commitOpts), TouchedPaths: touched})
+		if len(commits) == req.Limit {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSONFields(w, r, commits, fields)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/mail"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type Person struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+// CommitTrailers holds the people named in a commit's trailer block.
+type CommitTrailers struct {
+	SignedOffBy  []Person `json:"signedOffBy,omitempty"`
+	CoAuthoredBy []Person `json:"coAuthoredBy,omitempty"`
+	ReviewedBy   []Person `json:"reviewedBy,omitempty"`
+}
+
+// parseTrailers reads the trailers from the last paragraph of message, the
+// way git interpret-trailers does: the paragraph counts only when every
+// non-empty line in it is a "Key: value" trailer. Keys are matched
+// case-insensitively.
+func parseTrailers(message string) CommitTrailers {
+	var trailers CommitTrailers
+
+	paragraphs := strings.Split(strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n")), "\n\n")
+	if len(paragraphs) < 2 {
+		return trailers
+	}
+	block := paragraphs[len(paragraphs)-1]
+
+	type trailer struct{ key, value string }
+	var parsed []trailer
+	for _, line := range strings.Split(block, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return CommitTrailers{}
+		}
+		parsed = append(parsed, trailer{strings.ToLower(key), strings.TrimSpace(value)})
+	}
+
+	for _, t := range parsed {
+		person, ok := parseTrailerPerson(t.value)
+		if !ok {
+			continue
+		}
+		switch t.key {
+		case "signed-off-by":
+			trailers.SignedOffBy = append(trailers.SignedOffBy, person)
+		case "co-authored-by":
+			trailers.CoAuthoredBy = append(trailers.CoAuthoredBy, person)
+		case "reviewed-by":
+			trailers.ReviewedBy = append(trailers.ReviewedBy, person)
+		}
+	}
+	return trailers
+}
+
+// parseTrailerPerson splits "Name <email>". A value without an address is
+// kept as a bare name.
+func parseTrailerPerson(value string) (Person, bool) {
+	if value == "" {
+		return Person{}, false
+	}
+	if addr, err := mail.ParseAddress(value); err == nil {
+		return Person{Name: addr.Name, Email: addr.Address}, true
+	}
+	if i := strings.LastIndex(value, "<"); i >= 0 && strings.HasSuffix(value, ">") {
+		return Person{Name: strings.TrimSpace(value[:i]), Email: value[i+1 : len(value)-1]}, true
+	}
+	return Person{Name: value}, true
+}
+
+func (t CommitTrailers) redacted() CommitTrailers {
+	redact := func(people []Person) []Person {
+		for i := range people {
+			people[i].Email = redactEmail(people[i].Email)
+		}
+		return people
+	}
+	return CommitTrailers{
+		SignedOffBy:  redact(t.SignedOffBy),
+		CoAuthoredBy: redact(t.CoAuthoredBy),
+		ReviewedBy:   redact(t.ReviewedBy),
+	}
+}
+
+// coAuthors returns the co-authors of c as signatures dated like the commit,
+// so they can be counted alongside its author.
+func coAuthors(c *object.Commit) []object.Signature {
+	var signatures []object.Signature
+	for _, person := range parseTrailers(c.Message).CoAuthoredBy {
+		signatures = append(signatures, object.Signature{Name: person.Name, Email: person.Email, When: c.Author.When})
+	}
+	return signatures
+}
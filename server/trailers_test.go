@@ -0,0 +1,130 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestParseTrailers(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    CommitTrailers
+	}{
+		{
+			name:    "several co-authors",
+			message: "Pair on parser\n\nCo-authored-by: Bob <bob@example.com>\nCo-authored-by: Carol <carol@example.com>\nCo-authored-by: Dave <dave@example.com>\n",
+			want: CommitTrailers{CoAuthoredBy: []Person{
+				{Name: "Bob", Email: "bob@example.com"},
+				{Name: "Carol", Email: "carol@example.com"},
+				{Name: "Dave", Email: "dave@example.com"},
+			}},
+		},
+		{
+			name:    "mixed kinds",
+			message: "Fix leak\n\nBody text.\n\nSigned-off-by: Alice <alice@example.com>\nCo-authored-by: Bob <bob@example.com>\nReviewed-by: Carol <carol@example.com>\n",
+			want: CommitTrailers{
+				SignedOffBy:  []Person{{Name: "Alice", Email: "alice@example.com"}},
+				CoAuthoredBy: []Person{{Name: "Bob", Email: "bob@example.com"}},
+				ReviewedBy:   []Person{{Name: "Carol", Email: "carol@example.com"}},
+			},
+		},
+		{
+			name:    "keys in mixed case",
+			message: "Fix leak\n\nsigned-off-by: Alice <alice@example.com>\nCO-AUTHORED-BY: Bob <bob@example.com>\nCo-Authored-By: Carol <carol@example.com>\n",
+			want: CommitTrailers{
+				SignedOffBy:  []Person{{Name: "Alice", Email: "alice@example.com"}},
+				CoAuthoredBy: []Person{{Name: "Bob", Email: "bob@example.com"}, {Name: "Carol", Email: "carol@example.com"}},
+			},
+		},
+		{
+			name:    "CRLF line endings",
+			message: "Fix leak\r\n\r\nSigned-off-by: Alice <alice@example.com>\r\n",
+			want:    CommitTrailers{SignedOffBy: []Person{{Name: "Alice", Email: "alice@example.com"}}},
+		},
+		{
+			name:    "bare name",
+			message: "Fix leak\n\nCo-authored-by: Bob\n",
+			want:    CommitTrailers{CoAuthoredBy: []Person{{Name: "Bob"}}},
+		},
+		{
+			name:    "trailer block not last paragraph",
+			message: "Fix leak\n\nCo-authored-by: Bob <bob@example.com>\n\nMore explanation after the trailers.\n",
+		},
+		{
+			name:    "last paragraph mixes prose and trailers",
+			message: "Fix leak\n\nThanks to Bob for the idea.\nCo-authored-by: Bob <bob@example.com>\n",
+		},
+		{
+			name:    "subject only",
+			message: "Signed-off-by: Alice <alice@example.com>\n",
+		},
+		{
+			name:    "unknown keys ignored",
+			message: "Fix leak\n\nFixes: #12\nSigned-off-by: Alice <alice@example.com>\n",
+			want:    CommitTrailers{SignedOffBy: []Person{{Name: "Alice", Email: "alice@example.com"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTrailers(tt.message); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTrailers = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreditCoauthors(t *testing.T) {
+	commit := func(message string) *object.Commit {
+		return &object.Commit{
+			Author:  object.Signature{Name: "Alice", Email: "alice@example.com", When: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+			Message: message,
+		}
+	}
+	tests := []struct {
+		name    string
+		message string
+		credit  bool
+		want    map[string]int
+	}{
+		{
+			name:    "credit off",
+			message: "Pair\n\nCo-authored-by: Bob <bob@example.com>\n",
+			want:    map[string]int{"alice@example.com": 1},
+		},
+		{
+			name:    "several co-authors",
+			message: "Pair\n\nCo-authored-by: Bob <bob@example.com>\nCo-authored-by: Carol <carol@example.com>\n",
+			credit:  true,
+			want:    map[string]int{"alice@example.com": 1, "bob@example.com": 1, "carol@example.com": 1},
+		},
+		{
+			name:    "duplicates and the author counted once",
+			message: "Pair\n\nCo-authored-by: Bob <bob@example.com>\nCo-authored-by: Bob <BOB@example.com>\nCo-authored-by: Alice <alice@example.com>\n",
+			credit:  true,
+			want:    map[string]int{"alice@example.com": 1, "bob@example.com": 1},
+		},
+		{
+			name:    "signed-off-by is not co-authorship",
+			message: "Fix\n\nSigned-off-by: Bob <bob@example.com>\n",
+			credit:  true,
+			want:    map[string]int{"alice@example.com": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authors := newAuthorCounter(&mailmap{byEmail: map[string]identity{}, byNameEmail: map[string]identity{}})
+			authors.addCommit(commit(tt.message), tt.credit)
+			got := map[string]int{}
+			for key, count := range authors.counts {
+				got[key] = count.Commits
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("counts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
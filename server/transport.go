@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// maxHTTPRedirects bounds how many redirects a clone over HTTP(S) follows,
+// e.g. from a vanity domain to the real host. 0 disables following them.
+var maxHTTPRedirects = envInt("GIT_HTTP_MAX_REDIRECTS", 10)
+
+var (
+	errRedirectLoop      = errors.New("redirect loop")
+	errTooManyRedirects  = errors.New("too many redirects")
+	errRedirectsDisabled = errors.New("redirects are disabled")
+)
+
+// checkRedirect tells a redirect loop apart from a long but legitimate chain
+// so the clone error can say which one happened.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if maxHTTPRedirects <= 0 {
+		return fmt.Errorf("%w: %s", errRedirectsDisabled, req.URL.Redacted())
+	}
+	for _, previous := range via {
+		if previous.URL.String() == req.URL.String() {
+			return fmt.Errorf("%w at %s", errRedirectLoop, req.URL.Redacted())
+		}
+	}
+	if len(via) > maxHTTPRedirects {
+		return fmt.Errorf("%w: stopped after %d", errTooManyRedirects, maxHTTPRedirects)
+	}
+	return nil
+}
+
+// installGitHTTPClient replaces go-git's default HTTP(S) transport with one
+// whose client applies checkRedirect.
+func installGitHTTPClient() {
+	c := githttp.NewClient(&http.Client{
+		Transport:     http.DefaultTransport,
+		CheckRedirect: checkRedirect,
+	})
+	client.InstallProtocol("http", c)
+	client.InstallProtocol("https", c)
+}
+
+// cloneErrorMessage explains the clone failures users can act on and falls
+// back to the raw error otherwise.
+func cloneErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, errCloneCancelled):
+		return "Clone cancelled"
+	case errors.Is(err, errRedirectLoop):
+		return "Clone failed: the repository URL redirects in a loop"
+	case errors.Is(err, errTooManyRedirects):
+		return fmt.Sprintf("Clone failed: the repository URL redirected more than %d times", maxHTTPRedirects)
+	case errors.Is(err, errRedirectsDisabled):
+		return fmt.Sprintf("Clone failed: the repository URL redirects and GIT_HTTP_MAX_REDIRECTS is 0 (%v)", err)
+	case errors.Is(err, transport.ErrRepositoryNotFound):
+		return "Clone failed: repository not found"
+	case errors.Is(err, errNoRefsFetched):
+		return "Clone failed: none of the requested refs exist on the remote"
+	}
+	return fmt.Sprintf("Clone failed: %v", err)
+}
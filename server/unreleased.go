@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type TagInfo struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+	Date string `json:"date"`
+}
+
+type UnreleasedResponse struct {
+	LatestTag    *TagInfo            `json:"latestTag"`
+	NoTags       bool                `json:"noTags"`
+	TotalCommits int                 `json:"totalCommits"`
+	Groups       map[string][]Commit `json:"groups"`
+}
+
+func UnreleasedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	commitOpts, err := parseCommitOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, _, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	tags, err := listTags(repo)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get tags: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := UnreleasedResponse{Groups: map[string][]Commit{}}
+	released := map[plumbing.Hash]bool{}
+	if len(tags) == 0 {
+		resp.NoTags = true
+	} else {
+		latest := tags[len(tags)-1]
+		resp.LatestTag = &TagInfo{
+			Name: latest.Name,
+			Hash: latest.Commit.Hash.String(),
+			Date: latest.Commit.Committer.When.Format(time.RFC3339),
+		}
+		released, err = ancestors(repo, latest.Commit.Hash)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to walk tag history: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	iter, err := commitLog(r.Context(), repo, "", filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if !filter.matches(c) {
+			return nil
+		}
+		if released[c.Hash] {
+			return nil
+		}
+
+		group := "other"
+		if cc, ok := parseConventionalCommit(c.Message); ok {
+			group = cc.Type
+		}
+		resp.Groups[group] = append(resp.Groups[group], newCommit(c, commitOpts))
+		resp.TotalCommits++
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, resp)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// maxVerifyProblems bounds the problems /verify lists; a badly broken store
+// would otherwise report every object below the first missing one.
+const maxVerifyProblems = 100
+
+type VerifyProblem struct {
+	// Kind is dangling-ref for a ref whose target does not exist,
+	// missing-object for an object something refers to that is not in the
+	// store, and corrupt-object for one that exists but cannot be read.
+	Kind         string `json:"kind"`
+	Object       string `json:"object,omitempty"`
+	Ref          string `json:"ref,omitempty"`
+	ReferencedBy string `json:"referencedBy,omitempty"`
+	Error        string `json:"error"`
+}
+
+type VerifyResponse struct {
+	RepoID   string          `json:"repoId"`
+	OK       bool            `json:"ok"`
+	Refs     int             `json:"refs"`
+	Commits  int             `json:"commits"`
+	Trees    int             `json:"trees"`
+	Blobs    int             `json:"blobs"`
+	Problems []VerifyProblem `json:"problems"`
+	// Truncated is set when more than maxVerifyProblems were found.
+	Truncated bool   `json:"truncated"`
+	Hint      string `json:"hint,omitempty"`
+}
+
+// verifier walks everything reachable from the refs and records the
+// objects it cannot read. Each object is checked once.
+type verifier struct {
+	repo    *git.Repository
+	resp    *VerifyResponse
+	checked map[plumbing.Hash]bool
+	// shallow holds the boundary commits of a shallow clone, whose parents
+	// are missing by design.
+	shallow map[plumbing.Hash]bool
+}
+
+func (v *verifier) report(problem VerifyProblem) {
+	if len(v.resp.Problems) == maxVerifyProblems {
+		v.resp.Truncated = true
+		return
+	}
+	v.resp.Problems = append(v.resp.Problems, problem)
+}
+
+// objectProblem classifies err from reading hash, which referrer points at.
+func (v *verifier) objectProblem(hash plumbing.Hash, referrer string, err error) {
+	kind := "corrupt-object"
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		kind = "missing-object"
+	}
+	v.report(VerifyProblem{Kind: kind, Object: hash.String(), ReferencedBy: referrer, Error: err.Error()})
+}
+
+// checkRef confirms ref resolves to an object and returns the commit it
+// leads to, peeling annotated tags, or the zero hash when it leads nowhere
+// to walk from.
+func (v *verifier) checkRef(ref *plumbing.Reference) plumbing.Hash {
+	v.resp.Refs++
+	name := ref.Name().String()
+	if ref.Type() == plumbing.SymbolicReference {
+		resolved, err := v.repo.Reference(ref.Name(), true)
+		if err != nil {
+			v.report(VerifyProblem{Kind: "dangling-ref", Ref: name, Error: fmt.Sprintf("target %s: %v", ref.Target(), err)})
+			return plumbing.ZeroHash
+		}
+		ref = resolved
+	}
+
+	hash := ref.Hash()
+	for {
+		obj, err := v.repo.Storer.EncodedObject(plumbing.AnyObject, hash)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			v.report(VerifyProblem{Kind: "dangling-ref", Ref: name, Object: hash.String(), Error: err.Error()})
+			return plumbing.ZeroHash
+		}
+		if err != nil {
+			v.report(VerifyProblem{Kind: "corrupt-object", Ref: name, Object: hash.String(), Error: err.Error()})
+			return plumbing.ZeroHash
+		}
+		if obj.Type() != plumbing.TagObject {
+			if obj.Type() != plumbing.CommitObject {
+				return plumbing.ZeroHash
+			}
+			return hash
+		}
+		tag, err := object.DecodeTag(v.repo.Storer, obj)
+		if err != nil {
+			v.report(VerifyProblem{Kind: "corrupt-object", Ref: name, Object: hash.String(), Error: err.Error()})
+			return plumbing.ZeroHash
+		}
+		hash = tag.Target
+	}
+}
+
+// checkCommits walks from the given commits through their parents, reading
+// every commit and its tree.
+func (v *verifier) checkCommits(ctx context.Context, from []plumbing.Hash) error {
+	type pending struct {
+		hash     plumbing.Hash
+		referrer string
+	}
+	var stack []pending
+	for _, hash := range from {
+		stack = append(stack, pending{hash: hash})
+	}
+	for len(stack) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if v.checked[next.hash] {
+			continue
+		}
+		v.checked[next.hash] = true
+
+		c, err := v.repo.CommitObject(next.hash)
+		if err != nil {
+			v.objectProblem(next.hash, next.referrer, err)
+			continue
+		}
+		v.resp.Commits++
+		v.checkTree(c.TreeHash, c.Hash.String())
+		if v.shallow[c.Hash] {
+			continue
+		}
+		for _, parent := range c.ParentHashes {
+			stack = append(stack, pending{hash: parent, referrer: c.Hash.String()})
+		}
+	}
+	return nil
+}
+
+// checkTree reads a tree and its subtrees and confirms that every blob they
+// list is in the store. Blobs are not read back, which would mean
+// decompressing the whole history.
+func (v *verifier) checkTree(hash plumbing.Hash, referrer string) {
+	if v.checked[hash] {
+		return
+	}
+	v.checked[hash] = true
+
+	tree, err := object.GetTree(v.repo.Storer, hash)
+	if err != nil {
+		v.objectProblem(hash, referrer, err)
+		return
+	}
+	v.resp.Trees++
+	for _, entry := range tree.Entries {
+		switch entry.Mode {
+		case filemode.Dir:
+			v.checkTree(entry.Hash, hash.String())
+		case filemode.Submodule:
+			// A gitlink names a commit in another repository.
+		default:
+			if v.checked[entry.Hash] {
+				continue
+			}
+			v.checked[entry.Hash] = true
+			if err := v.repo.Storer.HasEncodedObject(entry.Hash); err != nil {
+				v.objectProblem(entry.Hash, hash.String(), err)
+				continue
+			}
+			v.resp.Blobs++
+		}
+	}
+}
+
+// VerifyHandler checks a repository's object store, in the spirit of
+// git fsck: every ref must resolve, and every commit, tree and blob
+// reachable from the refs must be present and, for commits, tags and trees,
+// readable. It is meant for diagnosing a repository that opens but fails
+// every history request, typically after an interrupted clone.
+func VerifyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	repo, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	resp := VerifyResponse{RepoID: repoID, Problems: []VerifyProblem{}}
+	v := &verifier{repo: repo, resp: &resp, checked: map[plumbing.Hash]bool{}, shallow: map[plumbing.Hash]bool{}}
+	for _, hash := range shallowBoundary(repo) {
+		v.shallow[plumbing.NewHash(hash)] = true
+	}
+
+	var tips []plumbing.Hash
+	refs, err := repo.References()
+	if err != nil {
+		v.report(VerifyProblem{Kind: "corrupt-object", Error: fmt.Sprintf("listing refs: %v", err)})
+	} else {
+		err = refs.ForEach(func(ref *plumbing.Reference) error {
+			if tip := v.checkRef(ref); !tip.IsZero() {
+				tips = append(tips, tip)
+			}
+			return nil
+		})
+		if err != nil {
+			v.report(VerifyProblem{Kind: "corrupt-object", Error: fmt.Sprintf("listing refs: %v", err)})
+		}
+	}
+
+	if err := v.checkCommits(r.Context(), tips); err != nil {
+		return
+	}
+
+	resp.OK = len(resp.Problems) == 0
+	if !resp.OK {
+		resp.Hint = "The repository is damaged, most likely by an interrupted clone. Remove its directory from the server's repos directory and clone it again with POST /repo; an existing directory is opened rather than cloned."
+	}
+	writeJSON(w, r, resp)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type WriteOnceFile struct {
+	File string `json:"file"`
+	// Hash and Added identify the commit that added the file, its only one.
+	Hash  string `json:"hash"`
+	Added string `json:"added"`
+}
+
+type WriteOnceResponse struct {
+	// Files counts the files at the tip that were considered. With since=
+	// or author= some of them may be neither write-once nor maintained,
+	// having no matching commit.
+	Files      int `json:"files"`
+	WriteOnce  int `json:"writeOnce"`
+	Maintained int `json:"maintained"`
+	// Percentage is the share of Files that are write-once.
+	Percentage     float64         `json:"percentage"`
+	WriteOnceFiles []WriteOnceFile `json:"writeOnceFiles"`
+}
+
+// WriteOnceHandler separates the files at the tip of a branch that were
+// added in one commit and never changed again, such as generated, vendored
+// or config files, from those still maintained. Merge commits are skipped,
+// since their diff against the first parent repeats the changes of the
+// merged branch. Files matching ignore= globs or the repository's
+// ignorePaths are left out, which is the place for expected write-once files
+// like a licence. It needs the full history, so shallow clones are refused
+// unless allowShallow=true.
+func WriteOnceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseCommitFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, repoID, ok := repoFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if refuseShallow(w, r, repo) {
+		return
+	}
+	filter.bind(r.Context(), repo)
+
+	branch := r.URL.Query().Get("branch")
+	tip, err := tipCommit(r.Context(), repo, branch)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+	tree, err := tip.Tree()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read tree: %v", err), http.StatusInternalServerError)
+		return
+	}
+	current := map[string]bool{}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if !isIgnoredPath(f.Name) && filter.includesFile(f.Name) {
+			current[f.Name] = true
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read tree: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	iter, err := commitLog(r.Context(), repo, branch, filter.logOptions())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get commit logs: %v", err), logErrorStatus(err))
+		return
+	}
+
+	commits := map[string]int{}
+	// first is the only commit of files seen once so far, which is the one
+	// that added them when the count stays at one.
+	first := map[string]WriteOnceFile{}
+	keep := func(c *object.Commit) bool {
+		return c.NumParents() <= 1 && filter.matches(c)
+	}
+	err = forEachCommitStats(repoID, iter, keep, func(c *object.Commit, modifications []FileModification) error {
+		for _, mod := range modifications {
+			if !current[mod.File] {
+				continue
+			}
+			commits[mod.File]++
+			if commits[mod.File] == 1 {
+				first[mod.File] = WriteOnceFile{File: mod.File, Hash: c.Hash.String(), Added: c.Author.When.Format(time.RFC3339)}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error processing commits: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := WriteOnceResponse{Files: len(current), WriteOnceFiles: []WriteOnceFile{}}
+	for file, n := range commits {
+		if n == 1 {
+			resp.WriteOnceFiles = append(resp.WriteOnceFiles, first[file])
+		} else {
+			resp.Maintained++
+		}
+	}
+	sort.Slice(resp.WriteOnceFiles, func(i, j int) bool {
+		return resp.WriteOnceFiles[i].File < resp.WriteOnceFiles[j].File
+	})
+	resp.WriteOnce = len(resp.WriteOnceFiles)
+	if resp.Files > 0 {
+		resp.Percentage = float64(resp.WriteOnce) / float64(resp.Files) * 100
+	}
+
+	writeJSON(w, r, resp)
+}